@@ -47,6 +47,10 @@ type Profile struct {
 	City      string `json:"city,omitempty"`
 	State     string `json:"state,omitempty"`
 	Locale    string `json:"locale,omitempty"`
+
+	// Extra holds the profile fields not mapped above (see extra.go)
+	Extra map[string]json.RawMessage `json:"-"`
+	raw   json.RawMessage
 }
 type Password struct {
 	Created                string       `json:"created,omitempty"`
@@ -1,32 +1,41 @@
 package accounts
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-
-	"io/ioutil"
 	"net/http"
-	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
 type AccountsAPI struct {
-	apiKey    string
-	userKey   string
-	secretKey string
-	apiDomain string
+	apiKey       string
+	userKey      string
+	secretKey    string
+	apiDomain    string
+	httpClient   *http.Client
+	signRequests bool
+	retryPolicy  RetryPolicy
+	rateLimiter  *RateLimiter
 }
 
-func NewAccountsAPI(apiKey, userKey, secretKey, apiDomain string) *AccountsAPI {
+func NewAccountsAPI(apiKey, userKey, secretKey, apiDomain string, opts ...Option) *AccountsAPI {
 
-	return &AccountsAPI{
+	a := &AccountsAPI{
 		apiKey:    apiKey,
 		userKey:   userKey,
 		secretKey: secretKey,
 		apiDomain: apiDomain,
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
 /* ╭──────────────────────────────────────────╮ */
@@ -44,6 +53,33 @@ func NewAccountsAPI(apiKey, userKey, secretKey, apiDomain string) *AccountsAPI {
 // - nextCursor: Cursor string for retrieving the next batch, empty if no more results
 // - error: Any error that occurred during the search
 func (a *AccountsAPI) SearchWithCursor(query string, limit int, cursor string) (Accounts, int, string, error) {
+	return a.SearchWithCursorContext(context.Background(), query, limit, cursor)
+}
+
+// SearchWithCursorContext is like SearchWithCursor but the request is bound to ctx
+func (a *AccountsAPI) SearchWithCursorContext(ctx context.Context, query string, limit int, cursor string) (Accounts, int, string, error) {
+	params := searchParams(query, limit, cursor)
+
+	// Send the request
+	body, err := a.requestRaw(ctx, "accounts.search", params)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	// Parse the JSON response
+	var response SearchResponse
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		log.Errorf("Error parsing JSON: %v", err)
+		logSearchDecodeError(body)
+		return nil, 0, "", err
+	}
+
+	return response.Results, response.TotalCount, response.Cursor(), nil
+}
+
+// searchParams builds the accounts.search parameters of a page
+func searchParams(query string, limit int, cursor string) map[string]string {
 	if limit < 1 {
 		limit = 1
 	}
@@ -54,18 +90,14 @@ func (a *AccountsAPI) SearchWithCursor(query string, limit int, cursor string) (
 	// }
 
 	// Add the limit to the query if this is the first call (no cursor provided)
+	// and the query does not set its own
 	if cursor == "" && limit > 0 {
 		// Only add limit to the query for the first call
-		query = fmt.Sprintf("%s limit %d", query, limit)
+		query = withLimit(query, limit)
 	}
 
 	// Prepare the API request parameters
-	method := "accounts.search"
-	params := map[string]string{
-		"apiKey":  a.apiKey,
-		"userKey": a.userKey,
-		"secret":  a.secretKey,
-	}
+	params := map[string]string{}
 
 	// If this is the first call, use query and openCursor=true
 	if cursor == "" {
@@ -76,72 +108,43 @@ func (a *AccountsAPI) SearchWithCursor(query string, limit int, cursor string) (
 		params["cursorId"] = cursor
 	}
 
-	// Build the request URL
-	baseURL := fmt.Sprintf("https://%s/%s", a.apiDomain, method)
-	data := url.Values{}
-	for key, value := range params {
-		data.Set(key, value)
-	}
-
-	// Send the POST request
-	resp, err := http.Post(baseURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
-	if err != nil {
-		return nil, 0, "", err
-	}
-	defer resp.Body.Close()
-
-	// Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, 0, "", err
-	}
+	return params
+}
 
-	// Parse the JSON response
-	var response SearchResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		log.Errorf("Error parsing JSON: %v", err)
-		
-		// Try to identify the problematic record
-		var debugResponse map[string]interface{}
-		if debugErr := json.Unmarshal(body, &debugResponse); debugErr == nil {
-			if results, ok := debugResponse["results"].([]interface{}); ok {
-				log.Errorf("Found %d results in response", len(results))
-				
-				// Check each result for markedForDeletion field
-				for i, result := range results {
-					if account, ok := result.(map[string]interface{}); ok {
-						uid, _ := account["UID"].(string)
-						
-						// Check for the data.account.markedForDeletion field
-						if data, ok := account["data"].(map[string]interface{}); ok {
-							if accountData, ok := data["account"].(map[string]interface{}); ok {
-								if markedForDeletion, exists := accountData["markedForDeletion"]; exists {
-									markedType := fmt.Sprintf("%T", markedForDeletion)
-									log.Errorf("Record %d (UID: %s) has markedForDeletion of type %s with value: %v", 
-										i, uid, markedType, markedForDeletion)
-									
-									// Log the entire account data for this problematic record
-									if accountJSON, _ := json.MarshalIndent(account, "", "  "); len(accountJSON) > 0 {
-										log.Errorf("Full record data:\n%s", string(accountJSON))
-									}
-								}
-							}
+// logSearchDecodeError tries to identify the records of a search response that could not be decoded
+func logSearchDecodeError(body []byte) {
+	var debugResponse map[string]interface{}
+	if debugErr := json.Unmarshal(body, &debugResponse); debugErr != nil {
+		return
+	}
+	results, ok := debugResponse["results"].([]interface{})
+	if !ok {
+		return
+	}
+	log.Errorf("Found %d results in response", len(results))
+
+	// Check each result for markedForDeletion field
+	for i, result := range results {
+		if account, ok := result.(map[string]interface{}); ok {
+			uid, _ := account["UID"].(string)
+
+			// Check for the data.account.markedForDeletion field
+			if data, ok := account["data"].(map[string]interface{}); ok {
+				if accountData, ok := data["account"].(map[string]interface{}); ok {
+					if markedForDeletion, exists := accountData["markedForDeletion"]; exists {
+						markedType := fmt.Sprintf("%T", markedForDeletion)
+						log.Errorf("Record %d (UID: %s) has markedForDeletion of type %s with value: %v",
+							i, uid, markedType, markedForDeletion)
+
+						// Log the entire account data for this problematic record
+						if accountJSON, _ := json.MarshalIndent(account, "", "  "); len(accountJSON) > 0 {
+							log.Errorf("Full record data:\n%s", string(accountJSON))
 						}
 					}
 				}
 			}
 		}
-		
-		return nil, 0, "", err
-	}
-
-	// Check for API errors
-	if response.ErrorCode != 0 {
-		return nil, 0, "", fmt.Errorf("API error %d: %s", response.ErrorCode, response.StatusReason)
 	}
-
-	return response.Results, response.TotalCount, response.NextCursor, nil
 }
 
 // SearchAll retrieves all accounts matching the specified query by making multiple paginated requests
@@ -155,6 +158,18 @@ func (a *AccountsAPI) SearchWithCursor(query string, limit int, cursor string) (
 // - totalCount: The total number of accounts matching the query
 // - error: Any error that occurred during the search
 func (a *AccountsAPI) SearchAll(query string, batchSize int, progressCallback func(fetched, total int)) (Accounts, int, error) {
+	return a.SearchAllContext(context.Background(), query, batchSize, progressCallback)
+}
+
+// SearchAllContext is like SearchAll but the pagination loop stops when ctx is done.
+// On cancellation the accounts fetched so far are returned with a *CanceledError
+// recording the number of fetched accounts and the cursor of the next page.
+func (a *AccountsAPI) SearchAllContext(ctx context.Context, query string, batchSize int, progressCallback func(fetched, total int)) (Accounts, int, error) {
+	return searchAll(ctx, a.searchPage, query, batchSize, progressCallback)
+}
+
+// searchAll fetches every page of a search with fetchPage. It backs SearchAllContext and SearchAllAs.
+func searchAll[T any](ctx context.Context, fetchPage pageFetcher[T], query string, batchSize int, progressCallback func(fetched, total int)) ([]T, int, error) {
 	if batchSize < 1 {
 		batchSize = 100 // Default batch size
 	}
@@ -165,7 +180,7 @@ func (a *AccountsAPI) SearchAll(query string, batchSize int, progressCallback fu
 	}
 
 	// Initial search to get the first batch and total count
-	accounts, totalCount, nextCursor, err := a.SearchWithCursor(query, batchSize, "")
+	accounts, totalCount, nextCursor, err := fetchPage(ctx, query, batchSize, "")
 	if err != nil {
 		return nil, 0, err
 	}
@@ -177,8 +192,17 @@ func (a *AccountsAPI) SearchAll(query string, batchSize int, progressCallback fu
 
 	// If there are more results, continue fetching
 	for nextCursor != "" {
+		// Stop between pages if the caller gave up
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return accounts, totalCount, &CanceledError{Method: "accounts.search", Fetched: len(accounts), Total: totalCount, Cursor: nextCursor, Err: ctxErr}
+		}
+
 		// Fetch the next batch
-		nextBatch, _, nextCursorVal, err := a.SearchWithCursor(query, batchSize, nextCursor)
+		nextBatch, _, nextCursorVal, err := fetchPage(ctx, query, batchSize, nextCursor)
+		var canceled *CanceledError
+		if errors.As(err, &canceled) {
+			return accounts, totalCount, &CanceledError{Method: canceled.Method, Fetched: len(accounts), Total: totalCount, Cursor: nextCursor, Err: canceled.Err}
+		}
 		if err != nil {
 			// Return what we've got so far along with the error
 			return accounts, totalCount, fmt.Errorf("error fetching batch with cursor %s: %w", nextCursor, err)
@@ -202,78 +226,40 @@ func (a *AccountsAPI) SearchAll(query string, batchSize int, progressCallback fu
 // Search maintains backward compatibility with existing code
 // Deprecated: Use SearchWithCursor or SearchAll instead
 func (a *AccountsAPI) Search(query string, limit int) (Accounts, int, error) {
-	accounts, totalCount, _, err := a.SearchWithCursor(query, limit, "")
+	return a.SearchContext(context.Background(), query, limit)
+}
+
+// SearchContext is like Search but the request is bound to ctx
+// Deprecated: Use SearchWithCursorContext or SearchAllContext instead
+func (a *AccountsAPI) SearchContext(ctx context.Context, query string, limit int) (Accounts, int, error) {
+	accounts, totalCount, _, err := a.SearchWithCursorContext(ctx, query, limit, "")
 	return accounts, totalCount, err
 }
 func (a *AccountsAPI) GetAccountInfo(UID string) (Account, error) {
+	return a.GetAccountInfoContext(context.Background(), UID)
+}
+func (a *AccountsAPI) GetAccountInfoContext(ctx context.Context, UID string) (Account, error) {
 
 	// Añadir parámetros
-	method := "accounts.getAccountInfo"
 	params := map[string]string{
 		"UID":     UID,
-		"apiKey":  a.apiKey,
-		"userKey": a.userKey,
-		"secret":  a.secretKey,
 		"include": "profile, data, preferences, emails, loginIDs",
 	}
 
-	// Preparar la URL de la solicitud
-	baseURL := fmt.Sprintf("https://%s/%s", a.apiDomain, method)
-	data := url.Values{}
-	for key, value := range params {
-		data.Set(key, value)
-	}
-
-	// Enviar la solicitud POST
-	resp, err := http.Post(baseURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
-	if err != nil {
-		return Account{}, err
-	}
-	defer resp.Body.Close()
-
-	// Leer la respuesta
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return Account{}, err
-	}
-
-	// Deserializar la respuesta JSON en SearchResponse
+	// Enviar la solicitud
 	var response GetAccountInfoResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		log.Errorf("Error parsing JSON for UID %s: %v", UID, err)
+	if err := a.request(ctx, "accounts.getAccountInfo", params, &response); err != nil {
 		return Account{}, err
 	}
 
-	// Verificar si hubo un error en la respuesta
-	if response.ErrorCode != 0 {
-		return Account{}, fmt.Errorf("API error %d: %s", response.ErrorCode, response.StatusReason)
-	}
-
 	// Create a new Account object
 	account := Account{
-		UID:     response.UID,
-		Profile: response.Profile,
-		Data:    response.Data,
-		Preferences: Preferences{
-			Marketing: Marketing{
-				Email: ConsentDetail{
-					IsConsentGranted: response.Preferences.Marketing.Email.IsConsentGranted,
-				},
-			},
-			Terms: Terms{
-				ToS: ConsentDetail{
-					IsConsentGranted: response.Preferences.Terms.ToS.IsConsentGranted,
-				},
-			},
-			Privacy: Privacy{
-				Livgolf: ConsentDetail{
-					IsConsentGranted: response.Preferences.Privacy.Livgolf.IsConsentGranted,
-				},
-			},
-		},
-		Created: response.Created,
-		Emails:  response.Emails,
+		UID:         response.UID,
+		Profile:     response.Profile,
+		Data:        response.Data,
+		Preferences: response.Preferences,
+		Created:     response.Created,
+		Emails:      response.Emails,
 		LoginIDs: LoginIDs{
 			Emails: response.LoginIDs.Emails,
 		},
@@ -289,207 +275,153 @@ func (a *AccountsAPI) GetAccountInfo(UID string) (Account, error) {
 	return account, nil
 }
 func (a *AccountsAPI) SetAccountInfo(account Account, isLite bool) (Account, error) {
+	return a.SetAccountInfoContext(context.Background(), account, isLite)
+}
+func (a *AccountsAPI) SetAccountInfoContext(ctx context.Context, account Account, isLite bool) (Account, error) {
 
-	// Añadir parámetros
-	method := "accounts.setAccountInfo"
-	params := map[string]string{
-		"UID":     account.UID,
-		"apiKey":  a.apiKey,
-		"userKey": a.userKey,
-		"secret":  a.secretKey,
-		// "profile": account.Profile.AsJSON(),
-		"data": account.Data.AsJSON(),
-	}
-
-	// Add isLite: true if isLite is true
-	if isLite {
-		params["isLite"] = "true"
-	}
-
-	if account.Data.Competition != nil {
-		// Check if both competition name and competition when are empty. if yes, set this fixed data section: {data: {competition: null}}
-		if account.Data.Competition.Name == "" && account.Data.Competition.When == "" {
-			params["data"] = `{"competition":null}`
-		}
-	}
-
-	// Preparar la URL de la solicitud
-	baseURL := fmt.Sprintf("https://%s/%s", a.apiDomain, method)
-	data := url.Values{}
-	for key, value := range params {
-		data.Set(key, value)
-	}
-
-	// Enviar la solicitud POST
-	resp, err := http.Post(baseURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
+	// Only data is sent
+	data, err := PatchFrom(account.Data)
 	if err != nil {
 		return Account{}, err
 	}
-	defer resp.Body.Close()
 
-	// Leer la respuesta
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return Account{}, err
+	// An empty competition (no name, no date) removes it: {data: {competition: null}}
+	if c := account.Data.Competition; c != nil && c.Name == "" && c.When == "" {
+		data = FieldPatch{"competition": Null}
 	}
 
-	// Deserializar la respuesta JSON en SearchResponse
-	var response SetAccountInfoResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
+	// Enviar la solicitud
+	if err := a.UpdateAccountInfoContext(ctx, account.UID, AccountUpdate{Data: data, IsLite: isLite}); err != nil {
 		return Account{}, err
 	}
 
-	// Verificar si hubo un error en la respuesta
-	if response.ErrorCode != 0 {
-		return Account{}, fmt.Errorf("API error %d: %s", response.ErrorCode, response.StatusReason)
-	}
-
 	return Account{UID: account.UID}, nil
 }
 func (a *AccountsAPI) ImportFullAccount(account Account) (Account, error) {
+	return a.ImportFullAccountContext(context.Background(), account)
+}
+func (a *AccountsAPI) ImportFullAccountContext(ctx context.Context, account Account) (Account, error) {
 	// Añadir parámetros
-	method := "accounts.importFullAccount"
 	params := map[string]string{
-		"apiKey":  a.apiKey,
-		"userKey": a.userKey,
-		"secret":  a.secretKey,
 		// "importPolicy": "upsert",
 		"importPolicy": "insert",
 		"account":      account.AsJSON(),
 	}
 
-	// Preparar la URL de la solicitud
-	baseURL := fmt.Sprintf("https://%s/%s", a.apiDomain, method)
-	data := url.Values{}
-	for key, value := range params {
-		data.Set(key, value)
-	}
-
-	// Enviar la solicitud POST
-	resp, err := http.Post(baseURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
-	if err != nil {
-		return Account{}, err
-	}
-	defer resp.Body.Close()
-
-	// Leer la respuesta
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return Account{}, err
-	}
-
-	// Deserializar la respuesta JSON en SearchResponse
+	// Enviar la solicitud
 	var response ImportFullAccountResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
+	if err := a.request(ctx, "accounts.importFullAccount", params, &response); err != nil {
 		return Account{}, err
 	}
 
-	// Verificar si hubo un error en la respuesta
-	if response.ErrorCode != 0 {
-		return Account{}, fmt.Errorf("API error %d: %s, %s\n\nDetails: %s", response.ErrorCode, response.StatusReason, response.ErrorMessage, response.ErrorDetails)
-	}
-
 	return Account{UID: response.UID}, nil
 }
+
+// DeleteOptions configures DeleteAccountWithOptions
+type DeleteOptions struct {
+	// SoftDelete sets data.account.markedForDeletion and markedForDeletionDate
+	// instead of deleting the account
+	SoftDelete bool
+	// DryRun only reports what would be deleted: nothing is changed
+	DryRun bool
+}
+
+// DeleteAccount deletes the account with accounts.deleteAccount
 func (a *AccountsAPI) DeleteAccount(UID string) (Account, error) {
+	return a.DeleteAccountContext(context.Background(), UID)
+}
+func (a *AccountsAPI) DeleteAccountContext(ctx context.Context, UID string) (Account, error) {
+	return a.DeleteAccountWithOptions(ctx, UID, DeleteOptions{})
+}
 
-	// Añadir parámetros
-	method := "accounts.setAccountInfo"
-	params := map[string]string{
-		"UID":     UID,
-		"apiKey":  a.apiKey,
-		"userKey": a.userKey,
-		"secret":  a.secretKey,
+// DeleteAccountWithOptions deletes, soft deletes or (dry run) reports the account
+// Returns:
+// - account: The account that was (or would be) deleted. In dry run mode it is
+// fetched with GetAccountInfo, so a missing UID returns a 403005 error.
+// - error: Any error that occurred
+func (a *AccountsAPI) DeleteAccountWithOptions(ctx context.Context, UID string, opts DeleteOptions) (Account, error) {
+	if opts.DryRun {
+		account, err := a.GetAccountInfoContext(ctx, UID)
+		if err != nil {
+			return Account{}, err
+		}
+		account.UID = UID
+		return account, nil
 	}
+	return a.deleteAccount(ctx, Account{UID: UID}, opts)
+}
 
-	// Preparar la URL de la solicitud
-	baseURL := fmt.Sprintf("https://%s/%s", a.apiDomain, method)
-	data := url.Values{}
-	for key, value := range params {
-		data.Set(key, value)
+// deleteAccount applies opts to an account already known to exist
+func (a *AccountsAPI) deleteAccount(ctx context.Context, account Account, opts DeleteOptions) (Account, error) {
+	if opts.DryRun {
+		return account, nil
 	}
 
-	// Enviar la solicitud POST
-	resp, err := http.Post(baseURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
-	if err != nil {
-		return Account{}, err
+	if opts.SoftDelete {
+		date := time.Now().UTC().Format(time.RFC3339)
+		update := AccountUpdate{Data: FieldPatch{
+			"account.markedForDeletion":     true,
+			"account.markedForDeletionDate": date,
+		}}
+		if err := a.UpdateAccountInfoContext(ctx, account.UID, update); err != nil {
+			return Account{}, err
+		}
+		account.Data.Account.MarkedForDeletion = true
+		account.Data.Account.MarkedForDeletionDate = date
+		return account, nil
 	}
-	defer resp.Body.Close()
 
-	// Leer la respuesta
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return Account{}, err
+	// Añadir parámetros
+	params := map[string]string{
+		"UID": account.UID,
 	}
 
-	// Deserializar la respuesta JSON en SearchResponse
+	// Enviar la solicitud
 	var response DeleteAccountResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
+	if err := a.request(ctx, "accounts.deleteAccount", params, &response); err != nil {
 		return Account{}, err
 	}
 
-	// Verificar si hubo un error en la respuesta
-	if response.ErrorCode != 0 {
-		return Account{}, fmt.Errorf("API error %d: %s, %s\n\nDetails: %s", response.ErrorCode, response.StatusReason, response.ErrorMessage, response.ErrorDetails)
-	}
-
-	return Account{UID: response.UID}, nil
+	return account, nil
 }
 
 /* ╭──────────────────────────────────────────╮ */
 /* │         IDXIMPORT ID  API CALLS          │ */
 /* ╰──────────────────────────────────────────╯ */
 func (a *AccountsAPI) SearchAccountsForIdxImportId(idxImportId string) ([]Account, error) {
+	return a.SearchAccountsForIdxImportIdContext(context.Background(), idxImportId)
+}
+func (a *AccountsAPI) SearchAccountsForIdxImportIdContext(ctx context.Context, idxImportId string) ([]Account, error) {
 
-	query := fmt.Sprintf("Select * from accounts where idxImportId='%s'", idxImportId)
-	// Añadir parámetros
-	method := "accounts.search"
-	params := map[string]string{
-		"apiKey":  a.apiKey,
-		"userKey": a.userKey,
-		"secret":  a.secretKey,
-		"query":   query,
-	}
-
-	// Preparar la URL de la solicitud
-	baseURL := fmt.Sprintf("https://%s/%s", a.apiDomain, method)
-	data := url.Values{}
-	for key, value := range params {
-		data.Set(key, value)
-	}
-
-	// Enviar la solicitud POST
-	resp, err := http.Post(baseURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
+	query, err := Select("*").From("accounts").Where(Eq("idxImportId", idxImportId)).Build()
 	if err != nil {
 		return []Account{}, err
 	}
-	defer resp.Body.Close()
 
-	// Leer la respuesta
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return []Account{}, err
+	// Añadir parámetros
+	params := map[string]string{
+		"query": query,
 	}
 
-	// Deserializar la respuesta JSON en SearchResponse
+	// Enviar la solicitud
 	var response SearchResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
+	if err := a.request(ctx, "accounts.search", params, &response); err != nil {
 		return []Account{}, err
 	}
 
-	// Verificar si hubo un error en la respuesta
-	if response.ErrorCode != 0 {
-		return []Account{}, fmt.Errorf("API error %d: %s\n\nDetails: %s", response.ErrorCode, response.StatusReason, response.ErrorDetails)
-	}
-
 	return response.Results, nil
 }
 func (a *AccountsAPI) DeleteAccountsForIdxImportId(idxImportId string) ([]Account, error) {
-	accounts, err := a.SearchAccountsForIdxImportId(idxImportId)
+	return a.DeleteAccountsForIdxImportIdContext(context.Background(), idxImportId)
+}
+func (a *AccountsAPI) DeleteAccountsForIdxImportIdContext(ctx context.Context, idxImportId string) ([]Account, error) {
+	return a.DeleteAccountsForIdxImportIdWithOptions(ctx, idxImportId, DeleteOptions{})
+}
+
+// DeleteAccountsForIdxImportIdWithOptions deletes, soft deletes or (dry run) lists
+// every account imported with idxImportId. Use it to roll back an import.
+func (a *AccountsAPI) DeleteAccountsForIdxImportIdWithOptions(ctx context.Context, idxImportId string, opts DeleteOptions) ([]Account, error) {
+	accounts, err := a.SearchAccountsForIdxImportIdContext(ctx, idxImportId)
 	if err != nil {
 		return []Account{}, err
 	}
@@ -499,55 +431,85 @@ func (a *AccountsAPI) DeleteAccountsForIdxImportId(idxImportId string) ([]Accoun
 		return []Account{}, nil
 	}
 	for i, account := range accounts {
-		_, err := a.DeleteAccount(account.UID)
+		_, err := a.deleteAccount(ctx, account, opts)
 
-		if err != nil {
+		switch {
+		case err != nil:
 			log.Errorf("i: %d - Error deleting account: %s\n", i, err)
-			return []Account{}, err
-		} else {
+			return accounts[:i], err
+		case opts.DryRun:
+			log.Printf("i: %d - [dry run] Account would be deleted: %s (%s)\n", i, account.Profile.Email, account.UID)
+		case opts.SoftDelete:
+			log.Printf("i: %d - Account marked for deletion: %s\n", i, account.Profile.Email)
+		default:
 			log.Printf("i: %d - Account deleted: %s\n", i, account.Profile.Email)
 		}
 	}
 	return accounts, nil
 }
 func (a *AccountsAPI) GetJWTPublicKey() (GetJWTPublicKeyResponse, error) {
-	// Añadir parámetros
-	method := "accounts.getJWTPublicKey"
-	params := map[string]string{
-		"apiKey": a.apiKey,
+	return a.GetJWTPublicKeyContext(context.Background())
+}
+func (a *AccountsAPI) GetJWTPublicKeyContext(ctx context.Context) (GetJWTPublicKeyResponse, error) {
+	// Enviar la solicitud (solo necesita el apiKey)
+	var response GetJWTPublicKeyResponse
+	if err := a.publicRequest(ctx, "accounts.getJWTPublicKey", nil, &response); err != nil {
+		return GetJWTPublicKeyResponse{}, err
 	}
 
-	// Preparar la URL de la solicitud
-	baseURL := fmt.Sprintf("https://%s/%s", a.apiDomain, method)
-	data := url.Values{}
-	for key, value := range params {
-		data.Set(key, value)
-	}
+	return response, nil
+}
 
-	// Enviar la solicitud POST
-	resp, err := http.Post(baseURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
-	if err != nil {
-		return GetJWTPublicKeyResponse{}, err
-	}
-	defer resp.Body.Close()
+// GetJWTPublicKeys returns every key the site signs id_tokens with (getJWTPublicKey
+// with V2=true), so a token can be verified with the key matching its kid
+func (a *AccountsAPI) GetJWTPublicKeys() ([]JWTPublicKey, error) {
+	return a.GetJWTPublicKeysContext(context.Background())
+}
 
-	// Leer la respuesta
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return GetJWTPublicKeyResponse{}, err
+// GetJWTPublicKeysContext is like GetJWTPublicKeys but the request is bound to ctx
+func (a *AccountsAPI) GetJWTPublicKeysContext(ctx context.Context) ([]JWTPublicKey, error) {
+	var response GetJWTPublicKeysResponse
+	if err := a.publicRequest(ctx, "accounts.getJWTPublicKey", map[string]string{"V2": "true"}, &response); err != nil {
+		return nil, err
 	}
 
-	// Deserializar la respuesta JSON en SearchResponse
-	var response GetJWTPublicKeyResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return GetJWTPublicKeyResponse{}, err
+	return response.Keys, nil
+}
+
+// GetJWT issues an id_token for an account, signed with the site key, for
+// backend flows without a browser session
+// Parameters:
+// - UID: The account the token is issued for (sub)
+// - fields: Account fields to add as claims (e.g. "profile.firstName", "data.favoriteTeam"); none when empty
+// - expiration: Validity of the token, in whole seconds; Gigya's default (5 minutes) when 0
+// Returns:
+// - The id_token, to verify with the jwt package
+func (a *AccountsAPI) GetJWT(UID string, fields []string, expiration time.Duration) (string, error) {
+	return a.GetJWTContext(context.Background(), UID, fields, expiration)
+}
+
+// GetJWTContext is like GetJWT but the request is bound to ctx
+func (a *AccountsAPI) GetJWTContext(ctx context.Context, UID string, fields []string, expiration time.Duration) (string, error) {
+	if expiration < 0 {
+		return "", fmt.Errorf("negative JWT expiration %v", expiration)
 	}
 
-	// Verificar si hubo un error en la respuesta
-	if response.ErrorCode != 0 {
-		return GetJWTPublicKeyResponse{}, fmt.Errorf("API error %d: %s\n\nDetails: %s", response.ErrorCode, response.StatusReason, response.ErrorDetails)
+	// Añadir parámetros
+	params := map[string]string{
+		"targetUID": UID,
+	}
+	if len(fields) > 0 {
+		params["fields"] = strings.Join(fields, ",")
+	}
+	if expiration > 0 {
+		seconds := int64((expiration + time.Second - 1) / time.Second)
+		params["expiration"] = strconv.FormatInt(seconds, 10)
 	}
 
-	return response, nil
+	// Enviar la solicitud
+	var response GetJWTResponse
+	if err := a.request(ctx, "accounts.getJWT", params, &response); err != nil {
+		return "", err
+	}
+	return response.IDToken, nil
 }
@@ -0,0 +1,166 @@
+package accounts
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │           AGGREGATE SEARCHES             │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// AggregateRow is a row of a GROUP BY / aggregate search, keyed by column name
+// as returned by Gigya, e.g. "data.favoriteTeam.name" or "count(*)".
+// Numbers are kept as json.Number.
+type AggregateRow map[string]interface{}
+
+type AggregateRows []AggregateRow
+
+// Column helpers for Select, e.g. Select("profile.country", Count("*")).GroupBy("profile.country")
+func Count(field string) string { return "count(" + field + ")" }
+func Sum(field string) string   { return "sum(" + field + ")" }
+func Min(field string) string   { return "min(" + field + ")" }
+func Max(field string) string   { return "max(" + field + ")" }
+func Avg(field string) string   { return "avg(" + field + ")" }
+
+// searchAggregateResponse keeps the results raw so they can be decoded into any type
+type searchAggregateResponse struct {
+	Results    json.RawMessage `json:"results"`
+	TotalCount int             `json:"totalCount"`
+}
+
+// SearchAggregate runs an aggregate query (count, sum, min, max, avg, with any
+// number of group by columns) and returns the rows as column → value maps
+// Returns:
+// - rows: The result rows
+// - totalCount: The totalCount reported by Gigya
+// - error: Any error that occurred
+func (a *AccountsAPI) SearchAggregate(ctx context.Context, query string) (AggregateRows, int, error) {
+	var response searchAggregateResponse
+	if err := a.request(ctx, "accounts.search", map[string]string{"query": query}, &response); err != nil {
+		return nil, 0, err
+	}
+
+	var rows AggregateRows
+	if len(response.Results) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(response.Results))
+		decoder.UseNumber()
+		if err := decoder.Decode(&rows); err != nil {
+			return nil, 0, err
+		}
+	}
+	return rows, response.TotalCount, nil
+}
+
+// SearchAggregateAs runs an aggregate query and decodes every row into T,
+// a struct whose json tags are the column names:
+//
+//	type CountryCount struct {
+//		Country string `json:"profile.country"`
+//		Count   int    `json:"count(*)"`
+//	}
+//	rows, _, err := accounts.SearchAggregateAs[CountryCount](ctx, api, query)
+func SearchAggregateAs[T any](ctx context.Context, a *AccountsAPI, query string) ([]T, int, error) {
+	var response searchAggregateResponse
+	if err := a.request(ctx, "accounts.search", map[string]string{"query": query}, &response); err != nil {
+		return nil, 0, err
+	}
+
+	var rows []T
+	if len(response.Results) > 0 {
+		if err := json.Unmarshal(response.Results, &rows); err != nil {
+			return nil, 0, err
+		}
+	}
+	return rows, response.TotalCount, nil
+}
+
+// String returns the column as text ("" when missing or null)
+func (r AggregateRow) String(column string) string {
+	switch v := r[column].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+// Int returns a numeric column as int64 (0 when missing or not numeric)
+func (r AggregateRow) Int(column string) int64 {
+	switch v := r[column].(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return int64(f)
+	case float64:
+		return int64(v)
+	case string:
+		i, _ := strconv.ParseInt(v, 10, 64)
+		return i
+	default:
+		return 0
+	}
+}
+
+// Float returns a numeric column as float64 (0 when missing or not numeric)
+func (r AggregateRow) Float(column string) float64 {
+	switch v := r[column].(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	default:
+		return 0
+	}
+}
+
+// Columns returns the sorted names of every column present in the rows
+func (rows AggregateRows) Columns() []string {
+	seen := map[string]bool{}
+	var columns []string
+	for _, row := range rows {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// ToCSV renders the rows with the given columns (all columns when none is given)
+func (rows AggregateRows) ToCSV(columns ...string) string {
+	if len(columns) == 0 {
+		columns = rows.Columns()
+	}
+
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write(columns)
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = row.String(column)
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package accounts_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/gigyatest"
+)
+
+func TestDeleteAccountWithOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        accounts.DeleteOptions
+		wantUpdates int
+		wantDeletes int
+		wantStored  bool
+	}{
+		{name: "dry run", opts: accounts.DeleteOptions{DryRun: true}, wantStored: true},
+		{name: "dry run wins over soft delete", opts: accounts.DeleteOptions{DryRun: true, SoftDelete: true}, wantStored: true},
+		{name: "soft delete", opts: accounts.DeleteOptions{SoftDelete: true}, wantUpdates: 1, wantStored: true},
+		{name: "hard delete", wantDeletes: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gigyatest.NewServer()
+			defer srv.Close()
+			srv.AddAccount(accounts.Account{UID: "uid-1", Profile: accounts.Profile{Email: "jane@example.com"}})
+
+			account, err := srv.AccountsAPI().DeleteAccountWithOptions(context.Background(), "uid-1", tt.opts)
+			if err != nil {
+				t.Fatalf("DeleteAccountWithOptions: %v", err)
+			}
+			if account.UID != "uid-1" {
+				t.Errorf("account UID = %q", account.UID)
+			}
+			if got := len(srv.Calls("accounts.setAccountInfo")); got != tt.wantUpdates {
+				t.Errorf("setAccountInfo called %d times, want %d", got, tt.wantUpdates)
+			}
+			if got := len(srv.Calls("accounts.deleteAccount")); got != tt.wantDeletes {
+				t.Errorf("deleteAccount called %d times, want %d", got, tt.wantDeletes)
+			}
+			stored, found := srv.Account("uid-1")
+			if found != tt.wantStored {
+				t.Fatalf("account stored = %v, want %v", found, tt.wantStored)
+			}
+
+			marked, _ := account.GetBool("data.account.markedForDeletion")
+			if !tt.opts.SoftDelete || tt.opts.DryRun {
+				if marked {
+					t.Error("account marked for deletion")
+				}
+				data, _ := stored["data"].(map[string]interface{})
+				if deletion, _ := data["account"].(map[string]interface{}); deletion["markedForDeletion"] != nil {
+					t.Errorf("stored data.account = %v", deletion)
+				}
+				return
+			}
+
+			if !marked || account.Data.Account.MarkedForDeletionDate == "" {
+				t.Errorf("returned account data = %+v, want both deletion fields", account.Data.Account)
+			}
+			data, _ := stored["data"].(map[string]interface{})
+			deletion, _ := data["account"].(map[string]interface{})
+			if deletion["markedForDeletion"] != true {
+				t.Errorf("stored data.account = %v, want markedForDeletion", deletion)
+			}
+			date, _ := deletion["markedForDeletionDate"].(string)
+			if _, err := time.Parse(time.RFC3339, date); err != nil {
+				t.Errorf("stored markedForDeletionDate = %q, want an RFC 3339 date", date)
+			}
+		})
+	}
+}
+
+func TestDeleteAccountDryRunMissing(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+
+	_, err := srv.AccountsAPI().DeleteAccountWithOptions(context.Background(), "uid-404", accounts.DeleteOptions{DryRun: true})
+	if !accounts.IsNotFound(err) {
+		t.Errorf("dry run of a missing UID error = %v, want a not found error", err)
+	}
+	if calls := srv.Calls("accounts.deleteAccount"); len(calls) != 0 {
+		t.Errorf("dry run called deleteAccount %d times", len(calls))
+	}
+}
+
+func TestDeleteAccountsForIdxImportIdWithOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        accounts.DeleteOptions
+		wantUpdates int
+		wantDeletes int
+	}{
+		{name: "dry run", opts: accounts.DeleteOptions{DryRun: true}},
+		{name: "soft delete", opts: accounts.DeleteOptions{SoftDelete: true}, wantUpdates: 2},
+		{name: "hard delete", wantDeletes: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gigyatest.NewServer()
+			defer srv.Close()
+			srv.AddAccount(map[string]interface{}{"UID": "uid-1", "idxImportId": "import-42"})
+			srv.AddAccount(map[string]interface{}{"UID": "uid-2", "idxImportId": "import-42"})
+			srv.AddAccount(map[string]interface{}{"UID": "uid-3", "idxImportId": "import-7"})
+
+			deleted, err := srv.AccountsAPI().DeleteAccountsForIdxImportIdWithOptions(context.Background(), "import-42", tt.opts)
+			if err != nil {
+				t.Fatalf("DeleteAccountsForIdxImportIdWithOptions: %v", err)
+			}
+			if len(deleted) != 2 {
+				t.Errorf("returned %d accounts, want 2", len(deleted))
+			}
+			if got := len(srv.Calls("accounts.setAccountInfo")); got != tt.wantUpdates {
+				t.Errorf("setAccountInfo called %d times, want %d", got, tt.wantUpdates)
+			}
+			deletes := srv.Calls("accounts.deleteAccount")
+			if len(deletes) != tt.wantDeletes {
+				t.Errorf("deleteAccount called %d times, want %d", len(deletes), tt.wantDeletes)
+			}
+			for _, call := range deletes {
+				if UID := call.Params.Get("UID"); UID == "uid-3" {
+					t.Error("deleted an account of another import")
+				}
+			}
+			if srv.Count() != 3-tt.wantDeletes {
+				t.Errorf("%d accounts left, want %d", srv.Count(), 3-tt.wantDeletes)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package accounts
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Gigya error codes with a dedicated helper
+const (
+	ErrorCodeAccountPendingRegistration = 206001
+	ErrorCodeAccountPendingVerification = 206002
+	ErrorCodeInvalidParameter           = 400006
+	ErrorCodeValidation                 = 400009
+	ErrorCodeNotFound                   = 403005
+	ErrorCodeInvalidLoginID             = 403042
+	ErrorCodeLoginIdentifierExists      = 403043
+	ErrorCodeOldPasswordUsed            = 401030
+	ErrorCodeRateLimited                = 403048
+	ErrorCodeGeneralServerError         = 500001
+)
+
+// APIError is returned when Gigya answers with a non-zero errorCode
+type APIError struct {
+	Method           string            `json:"-"`
+	ErrorCode        int               `json:"errorCode"`
+	StatusCode       int               `json:"statusCode"`
+	StatusReason     string            `json:"statusReason"`
+	ErrorMessage     string            `json:"errorMessage,omitempty"`
+	ErrorDetails     string            `json:"errorDetails,omitempty"`
+	CallID           string            `json:"callId"`
+	ValidationErrors []ValidationError `json:"validationErrors,omitempty"`
+	// RetryAfter is the delay requested by the Retry-After header, if any
+	RetryAfter time.Duration `json:"-"`
+}
+
+// ValidationError describes a single field rejected by Gigya (errorCode 400009)
+type ValidationError struct {
+	ErrorCode int    `json:"errorCode"`
+	Message   string `json:"message"`
+	FieldName string `json:"fieldName"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("API error %d: %s", e.ErrorCode, e.StatusReason)
+	if e.ErrorMessage != "" && e.ErrorMessage != e.StatusReason {
+		msg += ", " + e.ErrorMessage
+	}
+	if e.ErrorDetails != "" {
+		msg += "\n\nDetails: " + e.ErrorDetails
+	}
+	for _, v := range e.ValidationErrors {
+		msg += fmt.Sprintf("\n  %s: %s (%d)", v.FieldName, v.Message, v.ErrorCode)
+	}
+	return msg
+}
+
+// ErrorCode returns the Gigya errorCode carried by err, or 0 if err is not an *APIError
+func ErrorCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode
+	}
+	return 0
+}
+
+// retryAfterOf returns the Retry-After delay carried by err, or 0
+func retryAfterOf(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// IsNotFound reports whether err is a 403005 (unknown UID / account not found) error
+func IsNotFound(err error) bool {
+	return ErrorCode(err) == ErrorCodeNotFound
+}
+
+// IsRateLimited reports whether err is a 403048 (rate limit exceeded) error
+func IsRateLimited(err error) bool {
+	return ErrorCode(err) == ErrorCodeRateLimited
+}
+
+// IsInvalidParameter reports whether err is a 400006 (invalid parameter value) error,
+// such as an expired search cursor
+func IsInvalidParameter(err error) bool {
+	return ErrorCode(err) == ErrorCodeInvalidParameter
+}
+
+// IsValidationError reports whether err is a 400009 (schema validation) error
+func IsValidationError(err error) bool {
+	return ErrorCode(err) == ErrorCodeValidation
+}
+
+// IsPendingRegistration reports whether err is a 206001 (account pending registration) error
+func IsPendingRegistration(err error) bool {
+	return ErrorCode(err) == ErrorCodeAccountPendingRegistration
+}
+
+// IsPendingVerification reports whether err is a 206002 (account pending verification) error
+func IsPendingVerification(err error) bool {
+	return ErrorCode(err) == ErrorCodeAccountPendingVerification
+}
+
+// IsInvalidLogin reports whether err is a 403042 (invalid loginID or password) error
+func IsInvalidLogin(err error) bool {
+	return ErrorCode(err) == ErrorCodeInvalidLoginID
+}
+
+// IsLoginIdentifierConflict reports whether err is a 403043 (login identifier exists) error.
+// The regToken returned with it gives the conflicting account with GetConflictingAccount.
+func IsLoginIdentifierConflict(err error) bool {
+	return ErrorCode(err) == ErrorCodeLoginIdentifierExists
+}
+
+// CanceledError is returned when the context of a call is canceled or its deadline expires.
+// Paginated calls (SearchAllContext) return the accounts fetched so far together with it,
+// and record how far the pagination got.
+type CanceledError struct {
+	Method  string // API method that was running, e.g. "accounts.search"
+	Fetched int    // Number of accounts fetched before the cancellation
+	Total   int    // Total number of accounts matching the query, when known
+	Cursor  string // Cursor of the next page that was not fetched, when known
+	Err     error  // context.Canceled or context.DeadlineExceeded
+}
+
+func (e *CanceledError) Error() string {
+	if e.Fetched > 0 || e.Cursor != "" {
+		return fmt.Sprintf("%s canceled after fetching %d of %d accounts: %v", e.Method, e.Fetched, e.Total, e.Err)
+	}
+	return fmt.Sprintf("%s canceled: %v", e.Method, e.Err)
+}
+
+// Unwrap allows errors.Is(err, context.Canceled) and errors.Is(err, context.DeadlineExceeded)
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,410 @@
+package accounts
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │            RESUMABLE EXPORTS             │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// ExportFormat is the format of the export output file
+type ExportFormat string
+
+const (
+	ExportJSONL ExportFormat = "jsonl" // One account JSON object per line
+	ExportCSV   ExportFormat = "csv"   // One account per row, columns from ExportOptions.CSVColumns
+)
+
+// Watermark fields supported by Export
+const (
+	WatermarkUID                  = "UID"
+	WatermarkLastUpdatedTimestamp = "lastUpdatedTimestamp"
+)
+
+// DefaultCSVColumns are exported when ExportOptions.CSVColumns is empty
+var DefaultCSVColumns = []string{"UID", "profile.email", "profile.firstName", "profile.lastName", "profile.country", "created", "lastUpdatedTimestamp"}
+
+// ExportOptions configures Export
+type ExportOptions struct {
+	// Query is the search query. When it has no "order by" clause, one on the
+	// watermark field is added so the export can be resumed without a cursor.
+	// An "order by" must sort on the watermark field first, ascending.
+	Query string
+	// BatchSize is the number of records per page (max 100)
+	BatchSize int
+	// Output is the file the accounts are appended to
+	Output string
+	// Overwrite replaces an existing Output that has no state file to resume
+	// from. Without it Export refuses to start rather than wipe the file.
+	Overwrite bool
+	// Format of the output file. Defaults to CSV for a ".csv" Output, JSONL otherwise.
+	Format ExportFormat
+	// CSVColumns are the dotted paths written as CSV columns (default DefaultCSVColumns)
+	CSVColumns []string
+	// StateFile stores the checkpoint after every page (default Output + ".state.json")
+	StateFile string
+	// Watermark is the field used to resume when the cursor expired:
+	// WatermarkUID (default) or WatermarkLastUpdatedTimestamp
+	Watermark string
+	// Progress is called after every page (can be nil)
+	Progress func(fetched, total int)
+}
+
+// ExportCheckpoint is the state saved after every page of an export
+type ExportCheckpoint struct {
+	Query      string       `json:"query"`
+	Format     ExportFormat `json:"format"`
+	Cursor     string       `json:"cursor,omitempty"`
+	Fetched    int          `json:"fetched"`
+	TotalCount int          `json:"totalCount"`
+	// OutputSize is the size of the output file when the checkpoint was saved.
+	// Anything written after it is discarded when resuming.
+	OutputSize int64 `json:"outputSize"`
+	// Watermark is the value of the watermark field of the last exported record
+	WatermarkField string `json:"watermarkField"`
+	Watermark      string `json:"watermark,omitempty"`
+	// WatermarkUIDs are the UIDs already exported with the watermark timestamp
+	WatermarkUIDs []string  `json:"watermarkUIDs,omitempty"`
+	Completed     bool      `json:"completed"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// LoadExportCheckpoint reads the checkpoint saved by Export
+func LoadExportCheckpoint(path string) (ExportCheckpoint, error) {
+	var state ExportCheckpoint
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+// Export appends every account matching opts.Query to opts.Output, saving a
+// checkpoint after every page. Running it again with the same options resumes
+// from the stored cursor, or, if the cursor expired, re-runs the query from the
+// stored watermark. Records written after the last checkpoint are discarded
+// before resuming, so none is duplicated or lost.
+// Returns:
+// - the last checkpoint saved
+// - error: Any error that occurred. The export can be resumed after it.
+func (a *AccountsAPI) Export(ctx context.Context, opts ExportOptions) (ExportCheckpoint, error) {
+	if opts.Output == "" {
+		return ExportCheckpoint{}, errors.New("export: Output is required")
+	}
+	if opts.StateFile == "" {
+		opts.StateFile = opts.Output + ".state.json"
+	}
+	if opts.Format == "" {
+		opts.Format = ExportJSONL
+		if strings.EqualFold(filepath.Ext(opts.Output), ".csv") {
+			opts.Format = ExportCSV
+		}
+	}
+	if opts.Watermark == "" {
+		opts.Watermark = WatermarkUID
+	}
+	if opts.Watermark != WatermarkUID && opts.Watermark != WatermarkLastUpdatedTimestamp {
+		return ExportCheckpoint{}, fmt.Errorf("export: unsupported watermark field %q", opts.Watermark)
+	}
+	if len(opts.CSVColumns) == 0 {
+		opts.CSVColumns = DefaultCSVColumns
+	}
+	if opts.BatchSize < 1 || opts.BatchSize > 100 {
+		opts.BatchSize = 100
+	}
+	query := opts.Query
+	if match := orderByPattern.FindStringSubmatch(query); match == nil {
+		query = fmt.Sprintf("%s order by %s", query, opts.Watermark)
+	} else if match[1] != opts.Watermark || strings.EqualFold(match[2], "desc") {
+		// The watermark only resumes correctly over records sorted by it
+		return ExportCheckpoint{}, fmt.Errorf("export: the query must be ordered by %s ascending, not %q", opts.Watermark, strings.TrimSpace(match[0]))
+	}
+
+	// Load the previous checkpoint, if any
+	state, err := LoadExportCheckpoint(opts.StateFile)
+	resuming := err == nil
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		state = ExportCheckpoint{Query: opts.Query, Format: opts.Format, WatermarkField: opts.Watermark}
+	case err != nil:
+		return state, fmt.Errorf("export: reading state file: %w", err)
+	case state.Query != opts.Query || state.Format != opts.Format || state.WatermarkField != opts.Watermark:
+		return state, fmt.Errorf("export: state file %s belongs to another export (query %q)", opts.StateFile, state.Query)
+	case state.Completed:
+		return state, nil
+	}
+
+	// A new export never wipes an existing file, unless asked to
+	flags := os.O_CREATE | os.O_RDWR
+	if !resuming && !opts.Overwrite {
+		flags |= os.O_EXCL
+	}
+	out, err := os.OpenFile(opts.Output, flags, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return state, fmt.Errorf("export: %s has no state file to resume from, set Overwrite to replace it: %w", opts.Output, err)
+	}
+	if err != nil {
+		return state, err
+	}
+	defer out.Close()
+
+	// Discard whatever was written after the last checkpoint, unless the output
+	// lost records the checkpoint counts
+	info, err := out.Stat()
+	if err != nil {
+		return state, err
+	}
+	if info.Size() < state.OutputSize {
+		return state, fmt.Errorf("export: %s has %d bytes, less than the %d of the checkpoint", opts.Output, info.Size(), state.OutputSize)
+	}
+	if err := out.Truncate(state.OutputSize); err != nil {
+		return state, err
+	}
+	if _, err := out.Seek(state.OutputSize, 0); err != nil {
+		return state, err
+	}
+	if !resuming {
+		// Checkpoint right away, so the output can be resumed from the start
+		if opts.Format == ExportCSV {
+			if err := writeChunk(out, &state, csvRows([][]string{opts.CSVColumns})); err != nil {
+				return state, err
+			}
+		}
+		if err := saveExportCheckpoint(opts.StateFile, &state); err != nil {
+			return state, err
+		}
+	}
+
+	// Resume from the stored cursor, or from the watermark when there is none
+	cursor := state.Cursor
+	pageQuery := query
+	if cursor == "" && state.Watermark != "" {
+		pageQuery = watermarkQuery(query, state.WatermarkField, state.Watermark)
+	}
+
+	for {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return state, &CanceledError{Method: "accounts.search", Fetched: state.Fetched, Total: state.TotalCount, Cursor: state.Cursor, Err: ctxErr}
+		}
+
+		newQuery := cursor == ""
+		page, total, nextCursor, err := a.SearchWithCursorContext(ctx, pageQuery, opts.BatchSize, cursor)
+		if resuming && !newQuery && IsInvalidParameter(err) {
+			// The stored cursor expired: run the query again from the watermark
+			if state.Watermark == "" && state.Fetched > 0 {
+				return state, fmt.Errorf("export: cursor expired and no watermark to resume from: %w", err)
+			}
+			log.Warnf("export: cursor expired (%v), resuming from %s %s", err, state.WatermarkField, state.Watermark)
+			resuming = false
+			cursor = ""
+			if state.Watermark != "" {
+				pageQuery = watermarkQuery(query, state.WatermarkField, state.Watermark)
+			}
+			continue
+		}
+		if err != nil {
+			return state, err
+		}
+		resuming = false
+
+		if newQuery {
+			// The total of a watermark query only counts what was not exported yet
+			state.TotalCount = state.Fetched + total
+			if pageQuery != query {
+				state.TotalCount -= len(state.WatermarkUIDs)
+			}
+		}
+
+		// Write the page as a single chunk, then checkpoint
+		chunk, err := exportChunk(page, &state, opts)
+		if err != nil {
+			return state, err
+		}
+		if err := writeChunk(out, &state, chunk); err != nil {
+			return state, err
+		}
+		state.Cursor = nextCursor
+		state.Completed = nextCursor == ""
+		if err := saveExportCheckpoint(opts.StateFile, &state); err != nil {
+			return state, err
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(state.Fetched, state.TotalCount)
+		}
+		if nextCursor == "" {
+			return state, nil
+		}
+		cursor = nextCursor
+	}
+}
+
+// orderByPattern finds the "order by" clause of a query, with its first field and direction
+var orderByPattern = regexp.MustCompile(`(?i)\border\s+by\s+([A-Za-z0-9_.]+)(?:\s+(asc|desc)\b)?`)
+
+// trailingClausePattern finds the first clause that follows the where conditions
+var trailingClausePattern = regexp.MustCompile(`(?i)\s(group\s+by|order\s+by|start|limit)\s`)
+
+// wherePattern finds the where keyword of a query
+var wherePattern = regexp.MustCompile(`(?i)\swhere\s`)
+
+// watermarkQuery restricts query to the records after the watermark
+func watermarkQuery(query, field, value string) string {
+	var after Condition = Gt(field, value)
+	if field == WatermarkLastUpdatedTimestamp {
+		ts, _ := strconv.ParseInt(value, 10, 64)
+		after = Gte(field, ts)
+	}
+	condition, _ := after.render()
+
+	head, tail := query, ""
+	if loc := trailingClausePattern.FindStringIndex(query); loc != nil {
+		head, tail = query[:loc[0]], query[loc[0]:]
+	}
+	if loc := wherePattern.FindStringIndex(head); loc != nil {
+		return head[:loc[1]] + condition + " and (" + head[loc[1]:] + ")" + tail
+	}
+	return head + " where " + condition + tail
+}
+
+// exportChunk renders the records of a page not exported yet and advances the watermark
+func exportChunk(page Accounts, state *ExportCheckpoint, opts ExportOptions) ([]byte, error) {
+	var rows [][]string
+	var lines bytes.Buffer
+	for _, account := range page {
+		if state.WatermarkField == WatermarkLastUpdatedTimestamp {
+			ts := strconv.FormatInt(account.LastUpdatedTimestamp, 10)
+			if ts == state.Watermark && containsString(state.WatermarkUIDs, account.UID) {
+				continue
+			}
+			if ts != state.Watermark {
+				state.Watermark, state.WatermarkUIDs = ts, nil
+			}
+			state.WatermarkUIDs = append(state.WatermarkUIDs, account.UID)
+		} else {
+			state.Watermark = account.UID
+		}
+		state.Fetched++
+
+		if opts.Format == ExportCSV {
+			row, err := csvRow(account, opts.CSVColumns)
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, row)
+			continue
+		}
+		line, err := json.Marshal(account)
+		if err != nil {
+			return nil, err
+		}
+		lines.Write(line)
+		lines.WriteByte('\n')
+	}
+
+	if opts.Format == ExportCSV {
+		return csvRows(rows), nil
+	}
+	return lines.Bytes(), nil
+}
+
+// csvRow extracts the columns of account
+func csvRow(account Account, columns []string) ([]string, error) {
+	raw, err := json.Marshal(account)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		value, ok := lookupPath(fields, column)
+		if !ok || value == nil {
+			continue
+		}
+		if s, isString := value.(string); isString {
+			row[i] = s
+			continue
+		}
+		encoded, _ := json.Marshal(value)
+		row[i] = string(encoded)
+	}
+	return row, nil
+}
+
+func csvRows(rows [][]string) []byte {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.WriteAll(rows)
+	return b.Bytes()
+}
+
+// writeChunk appends chunk to the output with a single write and syncs it to disk
+func writeChunk(out *os.File, state *ExportCheckpoint, chunk []byte) error {
+	if len(chunk) == 0 {
+		return nil
+	}
+	if _, err := out.Write(chunk); err != nil {
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		return err
+	}
+	state.OutputSize += int64(len(chunk))
+	return nil
+}
+
+// saveExportCheckpoint replaces the state file atomically (write to a temp file, then rename)
+func saveExportCheckpoint(path string, state *ExportCheckpoint) error {
+	state.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,401 @@
+package accounts_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/gigyatest"
+)
+
+// exportedUIDs reads the UIDs of a JSONL export, in order
+func exportedUIDs(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var UIDs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var account accounts.Account
+		if err := json.Unmarshal(scanner.Bytes(), &account); err != nil {
+			t.Fatalf("invalid export line %q: %v", scanner.Text(), err)
+		}
+		UIDs = append(UIDs, account.UID)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return UIDs
+}
+
+// checkExported fails unless path holds every UID from uid-000 to uid-(n-1) exactly once, in order
+func checkExported(t *testing.T, path string, n int) {
+	t.Helper()
+	UIDs := exportedUIDs(t, path)
+	if len(UIDs) != n {
+		t.Fatalf("exported %d accounts, want %d: %v", len(UIDs), n, UIDs)
+	}
+	for i, UID := range UIDs {
+		if want := fmt.Sprintf("uid-%03d", i); UID != want {
+			t.Fatalf("line %d = %s, want %s", i, UID, want)
+		}
+	}
+}
+
+// exportUntil runs an export canceled once it fetched stop accounts
+func exportUntil(t *testing.T, api *accounts.AccountsAPI, opts accounts.ExportOptions, stop int) accounts.ExportCheckpoint {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts.Progress = func(fetched, total int) {
+		if fetched >= stop {
+			cancel()
+		}
+	}
+
+	state, err := api.Export(ctx, opts)
+	var canceled *accounts.CanceledError
+	if !errors.As(err, &canceled) {
+		t.Fatalf("Export error = %v, want a *CanceledError", err)
+	}
+	if state.Completed || state.Fetched != stop {
+		t.Fatalf("canceled export state = %+v, want %d fetched and not completed", state, stop)
+	}
+	return state
+}
+
+func TestExport(t *testing.T) {
+	srv := newSearchServer(t, 25)
+	api := srv.AccountsAPI()
+	output := filepath.Join(t.TempDir(), "accounts.jsonl")
+
+	state, err := api.Export(context.Background(), accounts.ExportOptions{Query: "select * from accounts", BatchSize: 10, Output: output})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if !state.Completed || state.Fetched != 25 || state.TotalCount != 25 {
+		t.Errorf("state = %+v, want 25 of 25 fetched and completed", state)
+	}
+	checkExported(t, output, 25)
+
+	saved, err := accounts.LoadExportCheckpoint(output + ".state.json")
+	if err != nil {
+		t.Fatalf("LoadExportCheckpoint: %v", err)
+	}
+	if !saved.Completed {
+		t.Errorf("saved checkpoint not completed: %+v", saved)
+	}
+
+	// A completed export is not run again
+	calls := len(srv.Calls("accounts.search"))
+	if _, err := api.Export(context.Background(), accounts.ExportOptions{Query: "select * from accounts", BatchSize: 10, Output: output}); err != nil {
+		t.Fatalf("Export again: %v", err)
+	}
+	if got := len(srv.Calls("accounts.search")); got != calls {
+		t.Errorf("completed export searched %d more times", got-calls)
+	}
+}
+
+func TestExportResume(t *testing.T) {
+	tests := []struct {
+		name string
+		stop int
+		// interrupt is applied between the canceled run and the resumed one
+		interrupt func(t *testing.T, srv *gigyatest.Server, output string)
+		// wantWatermark is true when the stored cursor fails and the query is run
+		// again from the watermark
+		wantWatermark bool
+	}{
+		{
+			name: "from the cursor",
+			stop: 10,
+		},
+		{
+			name: "from the watermark when the cursor expired",
+			stop: 10,
+			interrupt: func(t *testing.T, srv *gigyatest.Server, output string) {
+				srv.ExpireCursors()
+			},
+			wantWatermark: true,
+		},
+		{
+			name: "discarding records written after the checkpoint",
+			stop: 20,
+			interrupt: func(t *testing.T, srv *gigyatest.Server, output string) {
+				f, err := os.OpenFile(output, os.O_APPEND|os.O_WRONLY, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				if _, err := f.WriteString(`{"UID":"uid-020"}` + "\n" + `{"UID":"uid-0`); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSearchServer(t, 25)
+			api := srv.AccountsAPI()
+			opts := accounts.ExportOptions{Query: "select * from accounts", BatchSize: 10, Output: filepath.Join(t.TempDir(), "accounts.jsonl")}
+
+			first := exportUntil(t, api, opts, tt.stop)
+			if first.Cursor == "" || first.Watermark != fmt.Sprintf("uid-%03d", tt.stop-1) {
+				t.Fatalf("checkpoint = %+v, want a cursor and the last exported UID as watermark", first)
+			}
+			if tt.interrupt != nil {
+				tt.interrupt(t, srv, opts.Output)
+			}
+
+			calls := len(srv.Calls("accounts.search"))
+			state, err := api.Export(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("resumed Export: %v", err)
+			}
+			if !state.Completed || state.Fetched != 25 || state.TotalCount != 25 {
+				t.Errorf("state = %+v, want 25 of 25 fetched and completed", state)
+			}
+			checkExported(t, opts.Output, 25)
+
+			resumed := srv.Calls("accounts.search")[calls:]
+			if cursor := resumed[0].Params.Get("cursorId"); cursor != first.Cursor {
+				t.Errorf("resumed with cursor %q, want the stored cursor %q", cursor, first.Cursor)
+			}
+			want := fmt.Sprintf(`UID > "uid-%03d"`, tt.stop-1)
+			got := len(resumed) > 1 && strings.Contains(resumed[1].Params.Get("query"), want)
+			if got != tt.wantWatermark {
+				t.Errorf("resumed searches = %v; want a second one with %s: %v", resumed, want, tt.wantWatermark)
+			}
+		})
+	}
+}
+
+func TestExportResumeRefused(t *testing.T) {
+	tests := []struct {
+		name string
+		// interrupt is applied between the canceled run and the resumed one
+		interrupt func(t *testing.T, srv *gigyatest.Server, opts accounts.ExportOptions)
+		wantCode  int
+		// wantSearches is the number of searches of the refused run
+		wantSearches int
+	}{
+		{
+			name: "search error other than an expired cursor",
+			interrupt: func(t *testing.T, srv *gigyatest.Server, opts accounts.ExportOptions) {
+				srv.InjectError("accounts.search", accounts.ErrorCodeGeneralServerError, 1)
+			},
+			wantCode:     accounts.ErrorCodeGeneralServerError,
+			wantSearches: 1,
+		},
+		{
+			name: "expired cursor without a watermark",
+			interrupt: func(t *testing.T, srv *gigyatest.Server, opts accounts.ExportOptions) {
+				state, err := accounts.LoadExportCheckpoint(opts.StateFile)
+				if err != nil {
+					t.Fatal(err)
+				}
+				state.Watermark = ""
+				encoded, _ := json.Marshal(state)
+				if err := os.WriteFile(opts.StateFile, encoded, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				srv.ExpireCursors()
+			},
+			wantCode:     accounts.ErrorCodeInvalidParameter,
+			wantSearches: 1,
+		},
+		{
+			name: "output shorter than the checkpoint",
+			interrupt: func(t *testing.T, srv *gigyatest.Server, opts accounts.ExportOptions) {
+				if err := os.Truncate(opts.Output, 10); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSearchServer(t, 25)
+			api := srv.AccountsAPI(accounts.WithRetryPolicy(accounts.RetryPolicy{MaxAttempts: 1}))
+			output := filepath.Join(t.TempDir(), "accounts.jsonl")
+			opts := accounts.ExportOptions{Query: "select * from accounts", BatchSize: 10, Output: output, StateFile: output + ".state.json"}
+
+			exportUntil(t, api, opts, 10)
+			tt.interrupt(t, srv, opts)
+			before, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			calls := len(srv.Calls("accounts.search"))
+			_, err = api.Export(context.Background(), opts)
+			if err == nil {
+				t.Fatal("resumed Export succeeded, want an error")
+			}
+			if code := accounts.ErrorCode(err); code != tt.wantCode {
+				t.Errorf("Export error = %v, want code %d", err, tt.wantCode)
+			}
+			if got := len(srv.Calls("accounts.search")) - calls; got != tt.wantSearches {
+				t.Errorf("refused export searched %d times, want %d", got, tt.wantSearches)
+			}
+			if after, _ := os.ReadFile(output); string(after) != string(before) {
+				t.Errorf("refused export changed the output:\n%s\nwas\n%s", after, before)
+			}
+		})
+	}
+}
+
+func TestExportResumeTimestampWatermark(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	// Three accounts per timestamp, so the first page ends in the middle of uid-009..uid-011
+	for i := 0; i < 25; i++ {
+		srv.AddAccount(map[string]interface{}{
+			"UID":                  fmt.Sprintf("uid-%03d", i),
+			"lastUpdated":          "2024-01-01T00:00:00.000Z",
+			"lastUpdatedTimestamp": 1700000000000 + i/3,
+		})
+	}
+	api := srv.AccountsAPI()
+	opts := accounts.ExportOptions{
+		Query:     "select * from accounts",
+		BatchSize: 10,
+		Output:    filepath.Join(t.TempDir(), "accounts.jsonl"),
+		Watermark: accounts.WatermarkLastUpdatedTimestamp,
+	}
+
+	first := exportUntil(t, api, opts, 10)
+	if first.Watermark != "1700000000003" || strings.Join(first.WatermarkUIDs, ",") != "uid-009" {
+		t.Fatalf("checkpoint watermark = %s %v, want 1700000000003 [uid-009]", first.Watermark, first.WatermarkUIDs)
+	}
+	srv.ExpireCursors()
+
+	state, err := api.Export(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("resumed Export: %v", err)
+	}
+	if !state.Completed || state.Fetched != 25 || state.TotalCount != 25 {
+		t.Errorf("state = %+v, want 25 of 25 fetched and completed", state)
+	}
+	checkExported(t, opts.Output, 25)
+}
+
+func TestExportCSVResume(t *testing.T) {
+	srv := newSearchServer(t, 25)
+	api := srv.AccountsAPI()
+	opts := accounts.ExportOptions{Query: "select * from accounts", BatchSize: 10, Output: filepath.Join(t.TempDir(), "accounts.csv"), CSVColumns: []string{"UID", "profile.email"}}
+
+	exportUntil(t, api, opts, 10)
+	if _, err := api.Export(context.Background(), opts); err != nil {
+		t.Fatalf("resumed Export: %v", err)
+	}
+
+	data, err := os.ReadFile(opts.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 26 || lines[0] != "UID,profile.email" {
+		t.Fatalf("export has %d lines starting with %q, want the header once and 25 rows", len(lines), lines[0])
+	}
+	for i, line := range lines[1:] {
+		if want := fmt.Sprintf("uid-%03d,user%d@example.com", i, i); line != want {
+			t.Fatalf("row %d = %q, want %q", i, line, want)
+		}
+	}
+}
+
+func TestExportExistingOutput(t *testing.T) {
+	srv := newSearchServer(t, 5)
+	api := srv.AccountsAPI()
+
+	tests := []struct {
+		name      string
+		overwrite bool
+		wantErr   bool
+	}{
+		{name: "refused", wantErr: true},
+		{name: "overwritten", overwrite: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "accounts.jsonl")
+			if err := os.WriteFile(output, []byte("precious\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := api.Export(context.Background(), accounts.ExportOptions{Query: "select * from accounts", Output: output, Overwrite: tt.overwrite})
+			if tt.wantErr {
+				if !errors.Is(err, os.ErrExist) {
+					t.Fatalf("Export error = %v, want os.ErrExist", err)
+				}
+				if data, _ := os.ReadFile(output); string(data) != "precious\n" {
+					t.Errorf("existing output changed to %q", data)
+				}
+				if _, err := os.Stat(output + ".state.json"); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("state file created: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Export: %v", err)
+			}
+			checkExported(t, output, 5)
+		})
+	}
+}
+
+func TestExportOrderBy(t *testing.T) {
+	srv := newSearchServer(t, 5)
+	api := srv.AccountsAPI()
+
+	tests := []struct {
+		name      string
+		query     string
+		watermark string
+		wantErr   bool
+	}{
+		{name: "added when missing", query: "select * from accounts"},
+		{name: "on the watermark", query: "select * from accounts order by UID"},
+		{name: "ascending", query: "select * from accounts ORDER BY UID ASC"},
+		{name: "timestamp watermark", query: "select * from accounts order by lastUpdatedTimestamp", watermark: accounts.WatermarkLastUpdatedTimestamp},
+		{name: "descending", query: "select * from accounts order by UID desc", wantErr: true},
+		{name: "other field", query: "select * from accounts order by profile.email", wantErr: true},
+		{name: "watermark second", query: "select * from accounts order by profile.email, UID", wantErr: true},
+		{name: "other watermark", query: "select * from accounts order by UID", watermark: accounts.WatermarkLastUpdatedTimestamp, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "accounts.jsonl")
+			_, err := api.Export(context.Background(), accounts.ExportOptions{Query: tt.query, Output: output, Watermark: tt.watermark})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Export succeeded, want an error")
+				}
+				if _, statErr := os.Stat(output); !errors.Is(statErr, os.ErrNotExist) {
+					t.Errorf("output created for a rejected query: %v", statErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Export: %v", err)
+			}
+			checkExported(t, output, 5)
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package accounts
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │              UNKNOWN FIELDS              │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// Profile, Data and Preferences (and its consent groups) only map the fields
+// of some sites. The fields they do not map are kept in their Extra map and
+// written back as they were read. They also keep the JSON they were decoded
+// from, to write back the unknown fields of their nested objects (such as
+// data.favoriteTeam.color), so decoding and encoding an account never drops
+// data. Objects the input did not have, such as a consent the account never
+// gave, are not added back while they are still zero.
+
+var (
+	profileFields     = jsonFieldNames(reflect.TypeOf(Profile{}))
+	dataFields        = jsonFieldNames(reflect.TypeOf(Data{}))
+	preferencesFields = jsonFieldNames(reflect.TypeOf(Preferences{}))
+	marketingFields   = jsonFieldNames(reflect.TypeOf(Marketing{}))
+	termsFields       = jsonFieldNames(reflect.TypeOf(Terms{}))
+	privacyFields     = jsonFieldNames(reflect.TypeOf(Privacy{}))
+)
+
+// MarshalJSON encodes the profile with its Extra fields
+func (p Profile) MarshalJSON() ([]byte, error) {
+	type plain Profile
+	return marshalWithExtra(plain(p), p.Extra, p.raw)
+}
+
+// UnmarshalJSON decodes the profile, keeping the unknown fields in Extra
+func (p *Profile) UnmarshalJSON(b []byte) error {
+	type plain Profile
+	var decoded plain
+	extra, err := unmarshalWithExtra(b, &decoded, profileFields)
+	if err != nil {
+		return err
+	}
+	*p = Profile(decoded)
+	p.Extra = extra
+	p.raw = append(json.RawMessage(nil), b...)
+	return nil
+}
+
+// MarshalJSON encodes the data with its Extra fields
+func (a Data) MarshalJSON() ([]byte, error) {
+	type plain Data
+	return marshalWithExtra(plain(a), a.Extra, a.raw)
+}
+
+// UnmarshalJSON decodes the data, keeping the unknown fields in Extra
+func (a *Data) UnmarshalJSON(b []byte) error {
+	type plain Data
+	var decoded plain
+	extra, err := unmarshalWithExtra(b, &decoded, dataFields)
+	if err != nil {
+		return err
+	}
+	*a = Data(decoded)
+	a.Extra = extra
+	a.raw = append(json.RawMessage(nil), b...)
+	return nil
+}
+
+// MarshalJSON encodes the preferences with their Extra fields
+func (p Preferences) MarshalJSON() ([]byte, error) {
+	type plain Preferences
+	return marshalWithExtra(plain(p), p.Extra, p.raw)
+}
+
+// UnmarshalJSON decodes the preferences, keeping the unknown fields in Extra
+func (p *Preferences) UnmarshalJSON(b []byte) error {
+	type plain Preferences
+	var decoded plain
+	extra, err := unmarshalWithExtra(b, &decoded, preferencesFields)
+	if err != nil {
+		return err
+	}
+	*p = Preferences(decoded)
+	p.Extra = extra
+	p.raw = append(json.RawMessage(nil), b...)
+	return nil
+}
+
+// MarshalJSON encodes the marketing consents with their Extra fields
+func (m Marketing) MarshalJSON() ([]byte, error) {
+	type plain Marketing
+	return marshalWithExtra(plain(m), m.Extra, m.raw)
+}
+
+// UnmarshalJSON decodes the marketing consents, keeping the unknown ones in Extra
+func (m *Marketing) UnmarshalJSON(b []byte) error {
+	type plain Marketing
+	var decoded plain
+	extra, err := unmarshalWithExtra(b, &decoded, marketingFields)
+	if err != nil {
+		return err
+	}
+	*m = Marketing(decoded)
+	m.Extra = extra
+	m.raw = append(json.RawMessage(nil), b...)
+	return nil
+}
+
+// MarshalJSON encodes the terms consents with their Extra fields
+func (t Terms) MarshalJSON() ([]byte, error) {
+	type plain Terms
+	return marshalWithExtra(plain(t), t.Extra, t.raw)
+}
+
+// UnmarshalJSON decodes the terms consents, keeping the unknown ones in Extra
+func (t *Terms) UnmarshalJSON(b []byte) error {
+	type plain Terms
+	var decoded plain
+	extra, err := unmarshalWithExtra(b, &decoded, termsFields)
+	if err != nil {
+		return err
+	}
+	*t = Terms(decoded)
+	t.Extra = extra
+	t.raw = append(json.RawMessage(nil), b...)
+	return nil
+}
+
+// MarshalJSON encodes the privacy consents with their Extra fields
+func (p Privacy) MarshalJSON() ([]byte, error) {
+	type plain Privacy
+	return marshalWithExtra(plain(p), p.Extra, p.raw)
+}
+
+// UnmarshalJSON decodes the privacy consents, keeping the unknown ones in Extra
+func (p *Privacy) UnmarshalJSON(b []byte) error {
+	type plain Privacy
+	var decoded plain
+	extra, err := unmarshalWithExtra(b, &decoded, privacyFields)
+	if err != nil {
+		return err
+	}
+	*p = Privacy(decoded)
+	p.Extra = extra
+	p.raw = append(json.RawMessage(nil), b...)
+	return nil
+}
+
+// marshalWithExtra encodes known, adds the extra fields it does not already have
+// and restores the unknown nested fields of raw, the JSON known was decoded from
+func marshalWithExtra(known interface{}, extra map[string]json.RawMessage, raw json.RawMessage) ([]byte, error) {
+	encoded, err := json.Marshal(known)
+	if err != nil || (len(extra) == 0 && len(raw) == 0) {
+		return encoded, err
+	}
+
+	fields, err := decodeFields(encoded)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range extra {
+		if _, exists := fields[key]; !exists {
+			fields[key] = value
+		}
+	}
+	if len(raw) > 0 {
+		original, err := decodeFields(raw)
+		if err != nil {
+			return nil, err
+		}
+		restoreUnknown(reflect.TypeOf(known), fields, original, true)
+	}
+	return json.Marshal(fields)
+}
+
+// unmarshalWithExtra decodes b into target and returns the fields not in known
+func unmarshalWithExtra(b []byte, target interface{}, known map[string]bool) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(b, target); err != nil {
+		return nil, err
+	}
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		return nil, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	var extra map[string]json.RawMessage
+	for key, value := range fields {
+		// encoding/json matches field names case-insensitively
+		if known[strings.ToLower(key)] {
+			continue
+		}
+		if extra == nil {
+			extra = map[string]json.RawMessage{}
+		}
+		extra[key] = value
+	}
+	return extra, nil
+}
+
+// restoreUnknown copies into encoded the fields of original that t does not map,
+// at every level of t. The unknown fields of a struct with an Extra map (ownsExtra)
+// are left to it, and so are the types encoding themselves. It also removes the
+// zero struct values that encoding adds when original did not have them.
+func restoreUnknown(t reflect.Type, encoded, original interface{}, ownsExtra bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		out, ok := encoded.(map[string]interface{})
+		in, inOK := original.(map[string]interface{})
+		if !ok || !inOK {
+			return
+		}
+		fields := jsonFields(t)
+		for name, fieldType := range fields {
+			value, exists := out[name]
+			if !exists {
+				continue
+			}
+			previous, existed := lookupFold(in, name)
+			if !existed {
+				// A new object: drop it while zero, else drop its own zero objects
+				if fieldType.Kind() == reflect.Struct && isZeroEncoding(fieldType, value) {
+					delete(out, name)
+				} else {
+					restoreUnknown(fieldType, value, map[string]interface{}{}, false)
+				}
+				continue
+			}
+			if !encodesItself(fieldType) {
+				restoreUnknown(fieldType, value, previous, false)
+			}
+		}
+		if ownsExtra {
+			return
+		}
+		for key, value := range in {
+			if lookupName(fields, key) != "" {
+				continue
+			}
+			if _, exists := out[key]; !exists {
+				out[key] = value
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		out, ok := encoded.([]interface{})
+		in, inOK := original.([]interface{})
+		if !ok || !inOK || encodesItself(t.Elem()) {
+			return
+		}
+		for i := 0; i < len(out) && i < len(in); i++ {
+			restoreUnknown(t.Elem(), out[i], in[i], false)
+		}
+	case reflect.Map:
+		out, ok := encoded.(map[string]interface{})
+		in, inOK := original.(map[string]interface{})
+		if !ok || !inOK || encodesItself(t.Elem()) {
+			return
+		}
+		for key, value := range out {
+			if previous, existed := in[key]; existed {
+				restoreUnknown(t.Elem(), value, previous, false)
+			}
+		}
+	}
+}
+
+// isZeroEncoding reports whether value is the encoding of the zero value of t
+func isZeroEncoding(t reflect.Type, value interface{}) bool {
+	encoded, err := json.Marshal(reflect.Zero(t).Interface())
+	if err != nil {
+		return false
+	}
+	zero, err := decodeValue(encoded)
+	return err == nil && reflect.DeepEqual(zero, value)
+}
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// encodesItself reports whether t (or *t) implements json.Marshaler
+func encodesItself(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType)
+}
+
+// lookupFold returns the value of key in object, matching the case as encoding/json does
+func lookupFold(object map[string]interface{}, key string) (interface{}, bool) {
+	if value, ok := object[key]; ok {
+		return value, true
+	}
+	for k, value := range object {
+		if strings.EqualFold(k, key) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// lookupName returns the name in fields matching key case-insensitively, or ""
+func lookupName(fields map[string]reflect.Type, key string) string {
+	if _, ok := fields[key]; ok {
+		return key
+	}
+	for name := range fields {
+		if strings.EqualFold(name, key) {
+			return name
+		}
+	}
+	return ""
+}
+
+// jsonFields returns the JSON names of the fields of a struct type and their
+// types. The fields of embedded structs are promoted, as encoding/json does.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			for name, fieldType := range jsonFields(field.Type) {
+				if _, exists := fields[name]; !exists {
+					fields[name] = fieldType
+				}
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// jsonFieldNames returns the lower-cased JSON names of the fields of a struct type
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := map[string]bool{}
+	for name := range jsonFields(t) {
+		names[strings.ToLower(name)] = true
+	}
+	return names
+}
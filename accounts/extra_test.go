@@ -0,0 +1,138 @@
+package accounts_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gigya-module-go/accounts"
+)
+
+// section decodes the given top-level object of an encoded account
+func section(t *testing.T, encoded []byte, name string) interface{} {
+	t.Helper()
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		t.Fatalf("invalid JSON %s: %v", encoded, err)
+	}
+	return fields[name]
+}
+
+func TestAccountRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		section string
+		input   string
+		// want is the section written back; the input itself when empty
+		want string
+	}{
+		{
+			name:    "unknown profile field",
+			section: "profile",
+			input:   `{"firstName":"Jane","nickname":"JJ","address":{"street":"Main","number":1}}`,
+		},
+		{
+			name:    "unknown nested data field",
+			section: "data",
+			input:   `{"favoriteTeam":{"name":"Crushers","since":"2023","color":"purple"},"loyalty":{"tier":"gold"}}`,
+		},
+		{
+			name:    "unknown field in a mapped array",
+			section: "data",
+			input:   `{"personalization":{"favoritesDisciplines":[{"ocsCode":"ATH","rank":1}]}}`,
+		},
+		{
+			name:    "unknown consent field",
+			section: "preferences",
+			input:   `{"terms":{"ToS":{"isConsentGranted":true,"docVersion":2,"customField":"x"}}}`,
+		},
+		{
+			name:    "consents of another site",
+			section: "preferences",
+			input:   `{"privacy":{"livgolf":{"isConsentGranted":true},"majesticks":{"isConsentGranted":false}},"other":{"isConsentGranted":true}}`,
+		},
+		{
+			name:    "absent consents are not added",
+			section: "preferences",
+			input:   `{"marketing":{"email":{"isConsentGranted":true}}}`,
+		},
+		{
+			name:    "empty consent is kept",
+			section: "preferences",
+			input:   `{"livx":{},"terms":{"ToS":{}}}`,
+			want:    `{"livx":{"isConsentGranted":false},"terms":{"ToS":{"isConsentGranted":false}}}`,
+		},
+		{
+			name:    "revoked consent is kept",
+			section: "preferences",
+			input:   `{"livx":{"isConsentGranted":false,"tags":["web"]}}`,
+		},
+		{
+			name:    "no consent at all",
+			section: "preferences",
+			input:   `{}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := []byte(`{"UID":"uid-1","` + tt.section + `":` + tt.input + `}`)
+			var account accounts.Account
+			if err := json.Unmarshal(input, &account); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			encoded, err := json.Marshal(account)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+
+			want := tt.want
+			if want == "" {
+				want = tt.input
+			}
+			var wantSection interface{}
+			if err := json.Unmarshal([]byte(want), &wantSection); err != nil {
+				t.Fatal(err)
+			}
+			if got := section(t, encoded, tt.section); !reflect.DeepEqual(got, wantSection) {
+				gotJSON, _ := json.Marshal(got)
+				t.Errorf("%s written back as %s, want %s", tt.section, gotJSON, want)
+			}
+		})
+	}
+}
+
+func TestAccountRoundTripEdits(t *testing.T) {
+	input := `{"UID":"uid-1",
+		"data":{"favoriteTeam":{"name":"Crushers","color":"purple"}},
+		"preferences":{"terms":{"ToS":{"isConsentGranted":true,"tags":["web"]}}}}`
+	var account accounts.Account
+	if err := json.Unmarshal([]byte(input), &account); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	account.Data.FavoriteTeam.Name = "Fireballs"
+	account.Preferences.Terms.ToS.Tags = nil
+	account.Preferences.Marketing.Email.IsConsentGranted = true
+
+	encoded, err := json.Marshal(account)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(encoded, &got); err != nil {
+		t.Fatal(err)
+	}
+	var want map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"data":{"favoriteTeam":{"name":"Fireballs","color":"purple"}},
+		"preferences":{"terms":{"ToS":{"isConsentGranted":true}},"marketing":{"email":{"isConsentGranted":true}}}}`), &want)
+
+	for _, name := range []string{"data", "preferences"} {
+		if !reflect.DeepEqual(got[name], want[name]) {
+			gotJSON, _ := json.Marshal(got[name])
+			wantJSON, _ := json.Marshal(want[name])
+			t.Errorf("%s = %s, want %s", name, gotJSON, wantJSON)
+		}
+	}
+}
@@ -2,12 +2,10 @@ package accounts
 
 import (
 	"bytes"
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"net/url"
 	"strconv"
 	"strings"
 )
@@ -44,6 +42,10 @@ type Data struct {
 
 	LiveLikeID    string `json:"liveLikeID,omitempty"`
 	LiveLikeToken string `json:"liveLikeToken,omitempty"`
+
+	// Extra holds the data fields of other sites, not mapped above (see extra.go)
+	Extra map[string]json.RawMessage `json:"-"`
+	raw   json.RawMessage
 }
 
 // FavoritesDiscipline represents an individual discipline that a user has marked as favorite
@@ -56,8 +58,8 @@ type FavoritesDiscipline struct {
 
 // Personalization contains user personalization preferences
 type Personalization struct {
-	SiteLanguageP24     string                `json:"siteLanguageP24,omitempty"`
-	SiteLanguage        string                `json:"siteLanguage,omitempty"`
+	SiteLanguageP24      string                `json:"siteLanguageP24,omitempty"`
+	SiteLanguage         string                `json:"siteLanguage,omitempty"`
 	FavoritesDisciplines []FavoritesDiscipline `json:"favoritesDisciplines,omitempty"`
 }
 type Utility struct {
@@ -85,7 +87,7 @@ func (d Data) GetMarkedForDeletion() string {
 	if d.Account.MarkedForDeletion == nil {
 		return ""
 	}
-	
+
 	switch v := d.Account.MarkedForDeletion.(type) {
 	case string:
 		return v
@@ -121,6 +123,10 @@ type Preferences struct {
 	Livx      struct {
 		ConsentDetail
 	} `json:"livx,omitempty"`
+
+	// Extra holds the consents not mapped above (see extra.go)
+	Extra map[string]json.RawMessage `json:"-"`
+	raw   json.RawMessage
 }
 
 // Privacy representa las preferencias de la cuenta
@@ -128,13 +134,25 @@ type Privacy struct {
 	Livgolf ConsentDetail `json:"livgolf,omitempty"`
 	// Majesticks   ConsentDetail `json:"majesticks,omitempty"`
 	// SportsBreaks ConsentDetail `json:"sportsBreaks,omitempty"`
+
+	// Extra holds the consents of other sites (see extra.go)
+	Extra map[string]json.RawMessage `json:"-"`
+	raw   json.RawMessage
 }
 type Terms struct {
 	ToS ConsentDetail `json:"ToS,omitempty"`
+
+	// Extra holds the consents of other sites (see extra.go)
+	Extra map[string]json.RawMessage `json:"-"`
+	raw   json.RawMessage
 }
 type Marketing struct {
 	Email        ConsentDetail `json:"email,omitempty"`
 	SportsBreaks ConsentDetail `json:"sportsBreaks,omitempty"`
+
+	// Extra holds the consents of other sites (see extra.go)
+	Extra map[string]json.RawMessage `json:"-"`
+	raw   json.RawMessage
 }
 
 // ConsentDetail representa los detalles de consentimiento
@@ -199,104 +217,38 @@ func (a GroupedLIVGolfItems) ToCSV() string {
 	return b.String()
 }
 func (a *AccountsAPI) SetAccountInfoLIV(account Account, isLite bool) (Account, error) {
+	return a.SetAccountInfoLIVContext(context.Background(), account, isLite)
+}
+func (a *AccountsAPI) SetAccountInfoLIVContext(ctx context.Context, account Account, isLite bool) (Account, error) {
 
-	dataAsJSON := account.Data.AsJSON()
-	if dataAsJSON == "{\"competition\":{},\"favoriteTeam\":{}}" {
-		dataAsJSON = `{"competition":{"name":null,"when":null},"favoriteTeam":{}}`
-	}
-
-	// Añadir parámetros
-	method := "accounts.setAccountInfo"
-	params := map[string]string{
-		"UID":     account.UID,
-		"apiKey":  a.apiKey,
-		"userKey": a.userKey,
-		"secret":  a.secretKey,
-		"profile": account.Profile.AsJSON(),
-		"data":    dataAsJSON,
-		// "data":   `{"favoriteTeam":{"name":null,"since":null}}`,
-		"isLite": strconv.FormatBool(isLite),
-	}
-
-	// Preparar la URL de la solicitud
-	baseURL := fmt.Sprintf("https://%s/%s", a.apiDomain, method)
-	data := url.Values{}
-	for key, value := range params {
-		data.Set(key, value)
-	}
-
-	// Enviar la solicitud POST
-	resp, err := http.Post(baseURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
+	profile, err := PatchFrom(account.Profile)
 	if err != nil {
 		return Account{}, err
 	}
-	defer resp.Body.Close()
-
-	// Leer la respuesta
-	body, err := ioutil.ReadAll(resp.Body)
+	data, err := PatchFrom(account.Data)
 	if err != nil {
 		return Account{}, err
 	}
 
-	// Deserializar la respuesta JSON en SearchResponse
-	var response SetAccountInfoResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return Account{}, err
+	// An empty competition clears its fields: {"competition":{"name":null,"when":null}}
+	if c := account.Data.Competition; c != nil && c.Name == "" && c.When == "" {
+		data.SetNull("competition.name").SetNull("competition.when")
 	}
 
-	// Verificar si hubo un error en la respuesta
-	if response.ErrorCode != 0 {
-		return Account{}, fmt.Errorf("API error %d: %s", response.ErrorCode, response.StatusReason)
+	// Enviar la solicitud
+	update := AccountUpdate{Profile: profile, Data: data, IsLite: isLite}
+	if err := a.UpdateAccountInfoContext(ctx, account.UID, update); err != nil {
+		return Account{}, err
 	}
 
-	return Account{UID: response.UID}, nil
+	return Account{UID: account.UID}, nil
 }
 func (a *AccountsAPI) SearchGrouped(query string) (GroupedLIVGolfItems, int, error) {
-
-	// Añadir parámetros
-	method := "accounts.search"
-	params := map[string]string{
-		"query":   query,
-		"apiKey":  a.apiKey,
-		"userKey": a.userKey,
-		"secret":  a.secretKey,
-	}
-	// Preparar la URL de la solicitud
-	baseURL := fmt.Sprintf("https://%s/%s", a.apiDomain, method)
-	data := url.Values{}
-	for key, value := range params {
-		data.Set(key, value)
-	}
-	total := 0
-
-	// Enviar la solicitud POST
-	resp, err := http.Post(baseURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
-	if err != nil {
-		return nil, total, err
-	}
-	defer resp.Body.Close()
-
-	// Leer la respuesta
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, total, err
-	}
-
-	// Deserializar la respuesta JSON en SearchResponse
-	var response SearchGroupedResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return nil, total, err
-	}
-
-	// Verificar si hubo un error en la respuesta
-	if response.ErrorCode != 0 {
-		return nil, total, fmt.Errorf("API error %d: %s", response.ErrorCode, response.StatusReason)
-	}
-	total = response.TotalCount
-
-	return response.Results, total, nil
+	return a.SearchGroupedContext(context.Background(), query)
+}
+func (a *AccountsAPI) SearchGroupedContext(ctx context.Context, query string) (GroupedLIVGolfItems, int, error) {
+	// Only decodes the LIV Golf columns, use SearchAggregate for any other grouping
+	return SearchAggregateAs[GroupedLIVGolfItem](ctx, a, query)
 }
 
 type SearchGroupedResponse struct {
@@ -366,59 +318,29 @@ func (account AccountWithArrays) GetGigyaAccount() Account {
 }
 
 func (a *AccountsAPI) FixAccountInfo(account Account, isLite bool) (Account, error) {
+	return a.FixAccountInfoContext(context.Background(), account, isLite)
+}
+func (a *AccountsAPI) FixAccountInfoContext(ctx context.Context, account Account, isLite bool) (Account, error) {
 
-	// Añadir parámetros
-
-	/*
-
-			            "emails": {
-		                "verified": [],
-		                "unverified": []
-		            },
-	*/
-	method := "accounts.setAccountInfo"
-	params := map[string]string{
-		"UID":          account.UID,
-		"apiKey":       a.apiKey,
-		"userKey":      a.userKey,
-		"secret":       a.secretKey,
-		"profile":      account.Profile.AsJSON(),
-		"emails":       `[{"verified": [` + account.Profile.Email + `]}}]`,
-		"isVerified":   `true`,
-		"loginIDs":     `["` + account.Profile.Email + `"]`,
-		"isRegistered": `true`,
-	}
-	// Preparar la URL de la solicitud
-	baseURL := fmt.Sprintf("https://%s/%s", a.apiDomain, method)
-	data := url.Values{}
-	for key, value := range params {
-		data.Set(key, value)
-	}
-
-	// Enviar la solicitud POST
-	resp, err := http.Post(baseURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
+	profile, err := PatchFrom(account.Profile)
 	if err != nil {
 		return Account{}, err
 	}
-	defer resp.Body.Close()
 
-	// Leer la respuesta
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return Account{}, err
+	// The profile email becomes a login ID and the account is marked verified and registered
+	update := AccountUpdate{
+		Profile:    profile,
+		IsVerified: Ptr(true),
+		Params:     map[string]string{"isRegistered": "true"},
 	}
-
-	// Deserializar la respuesta JSON en SearchResponse
-	var response SetAccountInfoResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return Account{}, err
+	if account.Profile.Email != "" {
+		update.AddLoginEmails = []string{account.Profile.Email}
 	}
 
-	// Verificar si hubo un error en la respuesta
-	if response.ErrorCode != 0 {
-		return Account{}, fmt.Errorf("API error %d: %s", response.ErrorCode, response.StatusReason)
+	// Enviar la solicitud
+	if err := a.UpdateAccountInfoContext(ctx, account.UID, update); err != nil {
+		return Account{}, err
 	}
 
-	return Account{UID: response.UID}, nil
+	return Account{UID: account.UID}, nil
 }
@@ -0,0 +1,222 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │            LOGIN AND SESSIONS            │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// Values of the targetEnv parameter
+const (
+	// TargetEnvMobile returns the session as sessionToken / sessionSecret
+	TargetEnvMobile = "mobile"
+	// TargetEnvBrowser returns the session as a cookie name / value
+	TargetEnvBrowser = "browser"
+)
+
+// SessionInfo is the session created by a login: a token and secret
+// (targetEnv mobile) or a cookie (targetEnv browser)
+type SessionInfo struct {
+	SessionToken  string      `json:"sessionToken,omitempty"`
+	SessionSecret string      `json:"sessionSecret,omitempty"`
+	ExpiresIn     json.Number `json:"expires_in,omitempty"` // Seconds, as returned by Gigya
+	CookieName    string      `json:"cookieName,omitempty"`
+	CookieValue   string      `json:"cookieValue,omitempty"`
+}
+
+// IsCookie reports whether the session was returned as a cookie
+func (s SessionInfo) IsCookie() bool {
+	return s.CookieName != ""
+}
+
+// LoginParams are the parameters of accounts.login
+type LoginParams struct {
+	LoginID  string
+	Password string
+	// TargetEnv is TargetEnvMobile (default) or TargetEnvBrowser
+	TargetEnv string
+	// SessionExpiration in seconds, as defined by Gigya (0: until the browser closes,
+	// -1: until the browser closes or logout, -2: as configured for the site). Not sent when nil.
+	SessionExpiration *int
+	// CaptchaToken and CaptchaType are passed through when the site requires a CAPTCHA
+	CaptchaToken string
+	CaptchaType  string
+	// RiskContext is passed through as JSON for risk based authentication
+	RiskContext map[string]interface{}
+	// Include lists the account sections to return, e.g. "profile,data"
+	Include string
+	// Params are sent as is, for parameters without a dedicated field
+	Params map[string]string
+}
+
+// NotifyLoginParams are the parameters of accounts.notifyLogin
+type NotifyLoginParams struct {
+	// SiteUID is the UID of the user in the site's own user management
+	SiteUID           string
+	NewUser           bool
+	TargetEnv         string
+	SessionExpiration *int
+	RegSource         string
+}
+
+// LoginResult is the outcome of Login and NotifyLogin
+type LoginResult struct {
+	UID         string
+	SessionInfo SessionInfo
+	// RegToken is returned with the 206001 / 206002 pending errors and the
+	// 403043 login identifier conflict, to continue the flow
+	RegToken string
+	// Account holds the sections requested with Include
+	Account Account
+}
+
+// ConflictingAccount is the existing account holding a login identifier (403043)
+type ConflictingAccount struct {
+	LoginID        string   `json:"loginID"`
+	LoginProviders []string `json:"loginProviders"`
+}
+
+// Login logs a user in with loginID and password.
+// On 206001 / 206002 / 403043 the error is returned together with the result,
+// whose RegToken continues the registration or the conflict resolution.
+func (a *AccountsAPI) Login(params LoginParams) (LoginResult, error) {
+	return a.LoginContext(context.Background(), params)
+}
+
+// LoginContext is like Login but the request is bound to ctx
+func (a *AccountsAPI) LoginContext(ctx context.Context, params LoginParams) (LoginResult, error) {
+	values := map[string]string{}
+	for key, value := range params.Params {
+		values[key] = value
+	}
+	values["loginID"] = params.LoginID
+	values["password"] = params.Password
+	values["targetEnv"] = targetEnv(params.TargetEnv)
+	if params.SessionExpiration != nil {
+		values["sessionExpiration"] = strconv.Itoa(*params.SessionExpiration)
+	}
+	if params.CaptchaToken != "" {
+		values["captchaToken"] = params.CaptchaToken
+	}
+	if params.CaptchaType != "" {
+		values["captchaType"] = params.CaptchaType
+	}
+	if params.RiskContext != nil {
+		if err := setJSONParam(values, "riskContext", params.RiskContext); err != nil {
+			return LoginResult{}, err
+		}
+	}
+	if params.Include != "" {
+		values["include"] = params.Include
+	}
+
+	var response LoginResponse
+	err := a.request(ctx, "accounts.login", values, &response)
+	return loginResult(response), err
+}
+
+// NotifyLogin creates a Gigya session for a user authenticated by the site itself
+func (a *AccountsAPI) NotifyLogin(params NotifyLoginParams) (LoginResult, error) {
+	return a.NotifyLoginContext(context.Background(), params)
+}
+
+// NotifyLoginContext is like NotifyLogin but the request is bound to ctx
+func (a *AccountsAPI) NotifyLoginContext(ctx context.Context, params NotifyLoginParams) (LoginResult, error) {
+	if params.SiteUID == "" {
+		return LoginResult{}, fmt.Errorf("notifyLogin: siteUID is required")
+	}
+	values := map[string]string{
+		"siteUID":   params.SiteUID,
+		"newUser":   strconv.FormatBool(params.NewUser),
+		"targetEnv": targetEnv(params.TargetEnv),
+	}
+	if params.SessionExpiration != nil {
+		values["sessionExpiration"] = strconv.Itoa(*params.SessionExpiration)
+	}
+	if params.RegSource != "" {
+		values["regSource"] = params.RegSource
+	}
+
+	var response LoginResponse
+	err := a.request(ctx, "accounts.notifyLogin", values, &response)
+	return loginResult(response), err
+}
+
+// Logout ends every session of the user
+func (a *AccountsAPI) Logout(UID string) error {
+	return a.LogoutContext(context.Background(), UID)
+}
+
+// LogoutContext is like Logout but the request is bound to ctx
+func (a *AccountsAPI) LogoutContext(ctx context.Context, UID string) error {
+	params := map[string]string{
+		"UID": UID,
+	}
+	return a.request(ctx, "accounts.logout", params, nil)
+}
+
+// VerifyLogin checks the user is logged in and returns the account
+// Parameters:
+// - UID: The user to verify
+// - include: The account sections to return, e.g. "profile,data" (Gigya's default when empty)
+func (a *AccountsAPI) VerifyLogin(UID string, include string) (Account, error) {
+	return a.VerifyLoginContext(context.Background(), UID, include)
+}
+
+// VerifyLoginContext is like VerifyLogin but the request is bound to ctx
+func (a *AccountsAPI) VerifyLoginContext(ctx context.Context, UID string, include string) (Account, error) {
+	params := map[string]string{
+		"UID": UID,
+	}
+	if include != "" {
+		params["include"] = include
+	}
+
+	var response LoginResponse
+	if err := a.request(ctx, "accounts.verifyLogin", params, &response); err != nil {
+		return Account{}, err
+	}
+	return response.Account, nil
+}
+
+// GetConflictingAccount returns the account already holding the login identifier
+// after a 403043 error
+// Parameters:
+// - regToken: The regToken returned with the 403043 error
+func (a *AccountsAPI) GetConflictingAccount(regToken string) (ConflictingAccount, error) {
+	return a.GetConflictingAccountContext(context.Background(), regToken)
+}
+
+// GetConflictingAccountContext is like GetConflictingAccount but the request is bound to ctx
+func (a *AccountsAPI) GetConflictingAccountContext(ctx context.Context, regToken string) (ConflictingAccount, error) {
+	params := map[string]string{
+		"regToken": regToken,
+	}
+
+	var response GetConflictingAccountResponse
+	if err := a.request(ctx, "accounts.getConflictingAccount", params, &response); err != nil {
+		return ConflictingAccount{}, err
+	}
+	return response.ConflictingAccount, nil
+}
+
+func loginResult(response LoginResponse) LoginResult {
+	return LoginResult{
+		UID:         response.UID,
+		SessionInfo: response.SessionInfo,
+		RegToken:    response.RegToken,
+		Account:     response.Account,
+	}
+}
+
+func targetEnv(env string) string {
+	if env == "" {
+		return TargetEnvMobile
+	}
+	return env
+}
@@ -0,0 +1,158 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │           PASSWORD MANAGEMENT            │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// ResetPasswordParams are the parameters of accounts.resetPassword.
+// Set LoginID to start a reset, or PasswordResetToken and NewPassword to complete it.
+type ResetPasswordParams struct {
+	// LoginID of the user whose password is reset
+	LoginID string
+	// NoEmail returns the passwordResetToken instead of emailing the reset link
+	NoEmail bool
+	// Email sends the reset link to this address instead of the account's
+	Email string
+	// Lang of the email
+	Lang string
+
+	// PasswordResetToken from the reset link (or from NoEmail)
+	PasswordResetToken string
+	// NewPassword to set with PasswordResetToken
+	NewPassword string
+}
+
+// ResetPasswordResult is the outcome of ResetPassword
+type ResetPasswordResult struct {
+	// PasswordResetToken when the reset was started with NoEmail
+	PasswordResetToken string
+	// UID of the account, when the reset was completed
+	UID string
+}
+
+// PasswordPolicyError is returned when Gigya rejects a new password: it does not
+// meet the site's complexity requirements (400009 on the password field) or was
+// used before (401030). It wraps the *APIError, so ErrorCode and errors.As still work.
+type PasswordPolicyError struct {
+	// Reason is Gigya's explanation, e.g. "Password does not meet complexity requirements"
+	Reason string
+	// Reused is true when the password was used before (401030)
+	Reused bool
+	Err    *APIError
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password policy violation: " + e.Reason
+}
+
+// Unwrap gives access to the *APIError
+func (e *PasswordPolicyError) Unwrap() error {
+	return e.Err
+}
+
+// IsPasswordPolicyViolation reports whether err is a *PasswordPolicyError
+func IsPasswordPolicyViolation(err error) bool {
+	var policyErr *PasswordPolicyError
+	return errors.As(err, &policyErr)
+}
+
+// ResetPassword starts a password reset (LoginID) or completes it
+// (PasswordResetToken + NewPassword). A rejected new password returns a *PasswordPolicyError.
+func (a *AccountsAPI) ResetPassword(params ResetPasswordParams) (ResetPasswordResult, error) {
+	return a.ResetPasswordContext(context.Background(), params)
+}
+
+// ResetPasswordContext is like ResetPassword but the request is bound to ctx
+func (a *AccountsAPI) ResetPasswordContext(ctx context.Context, params ResetPasswordParams) (ResetPasswordResult, error) {
+	values := map[string]string{}
+	switch {
+	case params.PasswordResetToken != "":
+		if params.NewPassword == "" {
+			return ResetPasswordResult{}, fmt.Errorf("resetPassword: NewPassword is required with PasswordResetToken")
+		}
+		values["passwordResetToken"] = params.PasswordResetToken
+		values["newPassword"] = params.NewPassword
+	case params.LoginID != "":
+		values["loginID"] = params.LoginID
+		if params.NoEmail {
+			values["sendEmail"] = "false"
+		}
+		if params.Email != "" {
+			values["email"] = params.Email
+		}
+	default:
+		return ResetPasswordResult{}, fmt.Errorf("resetPassword: LoginID or PasswordResetToken is required")
+	}
+	if params.Lang != "" {
+		values["lang"] = params.Lang
+	}
+
+	var response ResetPasswordResponse
+	if err := a.request(ctx, "accounts.resetPassword", values, &response); err != nil {
+		return ResetPasswordResult{}, passwordError(err)
+	}
+	return ResetPasswordResult{PasswordResetToken: response.PasswordResetToken, UID: response.UID}, nil
+}
+
+// SetPassword changes the password of an account with setAccountInfo.
+// When currentPassword is empty, securityOverride is sent so the partner secret
+// is enough to change it.
+// Parameters:
+// - UID: The account to update
+// - currentPassword: The current password, or empty
+// - newPassword: The new password
+// Returns:
+// - error: A *PasswordPolicyError when the new password is rejected
+func (a *AccountsAPI) SetPassword(UID, currentPassword, newPassword string) error {
+	return a.SetPasswordContext(context.Background(), UID, currentPassword, newPassword)
+}
+
+// SetPasswordContext is like SetPassword but the request is bound to ctx
+func (a *AccountsAPI) SetPasswordContext(ctx context.Context, UID, currentPassword, newPassword string) error {
+	params := map[string]string{
+		"UID":         UID,
+		"newPassword": newPassword,
+	}
+	if currentPassword != "" {
+		params["password"] = currentPassword
+	} else {
+		params["securityOverride"] = "true"
+	}
+
+	var response SetAccountInfoResponse
+	if err := a.request(ctx, "accounts.setAccountInfo", params, &response); err != nil {
+		return passwordError(err)
+	}
+	return nil
+}
+
+// passwordError turns the Gigya errors caused by the password policy into a *PasswordPolicyError
+func passwordError(err error) error {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	switch apiErr.ErrorCode {
+	case ErrorCodeOldPasswordUsed:
+		reason := apiErr.ErrorMessage
+		if reason == "" {
+			reason = "the password was used before"
+		}
+		return &PasswordPolicyError{Reason: reason, Reused: true, Err: apiErr}
+	case ErrorCodeValidation:
+		for _, v := range apiErr.ValidationErrors {
+			if strings.EqualFold(v.FieldName, "password") || strings.EqualFold(v.FieldName, "newPassword") {
+				return &PasswordPolicyError{Reason: v.Message, Err: apiErr}
+			}
+		}
+	}
+	return err
+}
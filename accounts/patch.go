@@ -0,0 +1,388 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │              ACCOUNT PATCH               │ */
+/* ╰──────────────────────────────────────────╯ */
+
+type patchOperation int
+
+const (
+	patchSet patchOperation = iota
+	patchDelete
+	patchAppend
+)
+
+type patchChange struct {
+	operation patchOperation
+	path      string
+	value     interface{}   // patchSet
+	values    []interface{} // patchAppend
+}
+
+// AccountPatch records field-level changes of an account with full dotted paths
+// such as "data.favoriteTeam.name", and renders them as the minimal setAccountInfo
+// payloads:
+//
+//	patch := accounts.NewAccountPatch().
+//		Set("data.favoriteTeam.name", "Crushers GC").
+//		Delete("data.competition").
+//		Append("data.visited", "Andalucia")
+//	err := api.PatchAccount(uid, patch)
+//
+// Paths start with profile, data, preferences or subscriptions; isVerified,
+// isActive, username and lang can be set too. Changes are applied in order:
+// a change replaces the earlier changes of the same path and of the paths
+// below it, so Set("data.a.b", x) then Delete("data.a") deletes data.a. A change
+// below a path set earlier to an object is merged into it; below a deleted path
+// (or one set to another value) it is an error, as a single setAccountInfo call
+// cannot remove an object and set its fields.
+type AccountPatch struct {
+	changes []patchChange
+}
+
+// NewAccountPatch returns an empty patch
+func NewAccountPatch() *AccountPatch {
+	return &AccountPatch{}
+}
+
+// Set sets path to value
+func (p *AccountPatch) Set(path string, value interface{}) *AccountPatch {
+	p.changes = append(p.changes, patchChange{operation: patchSet, path: path, value: value})
+	return p
+}
+
+// Delete sets path to null, which removes it
+func (p *AccountPatch) Delete(path string) *AccountPatch {
+	p.changes = append(p.changes, patchChange{operation: patchDelete, path: path})
+	return p
+}
+
+// Append adds values at the end of the array at path. Gigya replaces arrays
+// as a whole, so the current array is read from the base given to Update,
+// unless the patch set or deleted path earlier.
+func (p *AccountPatch) Append(path string, values ...interface{}) *AccountPatch {
+	p.changes = append(p.changes, patchChange{operation: patchAppend, path: path, values: values})
+	return p
+}
+
+// Empty reports whether the patch has no changes
+func (p *AccountPatch) Empty() bool {
+	return len(p.changes) == 0
+}
+
+// Paths returns the changed paths, sorted
+func (p *AccountPatch) Paths() []string {
+	seen := map[string]bool{}
+	var paths []string
+	for _, change := range p.changes {
+		if !seen[change.path] {
+			seen[change.path] = true
+			paths = append(paths, change.path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (p *AccountPatch) hasAppends() bool {
+	for _, change := range p.changes {
+		if change.operation == patchAppend {
+			return true
+		}
+	}
+	return false
+}
+
+// Update renders the patch as an AccountUpdate
+// Parameters:
+// - base: The current account (Account, raw map...), needed by Append; nil when the patch has no appends
+func (p *AccountPatch) Update(base interface{}) (AccountUpdate, error) {
+	var baseFields map[string]interface{}
+	if p.hasAppends() {
+		if base == nil {
+			return AccountUpdate{}, fmt.Errorf("account patch: Append needs the current account as base")
+		}
+		encoded, err := json.Marshal(base)
+		if err != nil {
+			return AccountUpdate{}, err
+		}
+		if err := json.Unmarshal(encoded, &baseFields); err != nil {
+			return AccountUpdate{}, fmt.Errorf("account patch: %T does not encode to a JSON object", base)
+		}
+	}
+
+	var update AccountUpdate
+	// Arrays already appended to, so several Append calls on a path add up
+	appended := map[string][]interface{}{}
+
+	for _, change := range p.changes {
+		section, field, _ := strings.Cut(change.path, ".")
+
+		var value interface{}
+		switch change.operation {
+		case patchSet:
+			value = change.value
+			forgetBelow(appended, change.path)
+			// A later Append adds to the array set here, not to the base one
+			if list, ok := arrayOf(value); ok {
+				appended[change.path] = list
+			}
+		case patchDelete:
+			value = Null
+			forgetBelow(appended, change.path)
+			appended[change.path] = []interface{}{}
+		case patchAppend:
+			current, ok := appended[change.path]
+			if !ok {
+				existing, _ := lookupPath(baseFields, change.path)
+				if existing != nil {
+					list, isList := existing.([]interface{})
+					if !isList {
+						return AccountUpdate{}, fmt.Errorf("account patch: %s is not an array", change.path)
+					}
+					current = append(current, list...)
+				}
+			}
+			current = append(current, change.values...)
+			appended[change.path] = current
+			value = current
+		}
+
+		switch section {
+		case "profile", "data", "preferences", "subscriptions":
+			if field == "" {
+				return AccountUpdate{}, fmt.Errorf("account patch: %q needs a field below the section", change.path)
+			}
+			if err := place(update.section(section), section, field, value); err != nil {
+				return AccountUpdate{}, fmt.Errorf("account patch: %s: %w", change.path, err)
+			}
+		case "isVerified", "isActive":
+			flag, ok := value.(bool)
+			if !ok || field != "" {
+				return AccountUpdate{}, fmt.Errorf("account patch: %s must be set to a bool", change.path)
+			}
+			if section == "isVerified" {
+				update.IsVerified = Ptr(flag)
+			} else {
+				update.IsActive = Ptr(flag)
+			}
+		case "username", "lang":
+			text, ok := value.(string)
+			if !ok || field != "" {
+				return AccountUpdate{}, fmt.Errorf("account patch: %s must be set to a string", change.path)
+			}
+			if section == "username" {
+				update.Username = Ptr(text)
+			} else {
+				update.Lang = Ptr(text)
+			}
+		default:
+			return AccountUpdate{}, fmt.Errorf("account patch: unsupported path %q", change.path)
+		}
+	}
+	return update, nil
+}
+
+// place sets field to value in the patch of section. It replaces the changes of
+// field and of the fields below it, and merges into the change of a parent set
+// to an object.
+func place(patch FieldPatch, section, field string, value interface{}) error {
+	forgetBelow(patch, field)
+
+	keys := strings.Split(field, ".")
+	for i := len(keys) - 1; i > 0; i-- {
+		parent := strings.Join(keys[:i], ".")
+		current, exists := patch[parent]
+		if !exists {
+			continue
+		}
+		object, err := objectOf(current)
+		if err != nil || object == nil {
+			return fmt.Errorf("%s.%s is deleted or not an object earlier in the patch", section, parent)
+		}
+		if err := setPath(object, strings.Join(keys[i:], "."), value); err != nil {
+			return err
+		}
+		patch[parent] = object
+		return nil
+	}
+	patch[field] = value
+	return nil
+}
+
+// forgetBelow removes path and the paths below it from changes
+func forgetBelow[V any](changes map[string]V, path string) {
+	for key := range changes {
+		if key == path || strings.HasPrefix(key, path+".") {
+			delete(changes, key)
+		}
+	}
+}
+
+// objectOf returns a copy of value as a decoded JSON object, nil when it is not one
+func objectOf(value interface{}) (map[string]interface{}, error) {
+	if object, ok := value.(map[string]interface{}); ok {
+		return copyObject(object), nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	object, err := decodeFields(encoded)
+	if err != nil {
+		return nil, nil
+	}
+	return object, nil
+}
+
+// arrayOf returns a copy of value as a decoded JSON array, false when it is not one
+func arrayOf(value interface{}) ([]interface{}, bool) {
+	if list, ok := value.([]interface{}); ok {
+		return append([]interface{}(nil), list...), true
+	}
+	if value == nil || reflect.TypeOf(value).Kind() != reflect.Slice {
+		return nil, false
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var list []interface{}
+	if err := json.Unmarshal(encoded, &list); err != nil {
+		return nil, false
+	}
+	return list, true
+}
+
+// section returns the patch of a section, creating it when needed
+func (u *AccountUpdate) section(name string) FieldPatch {
+	target := map[string]*FieldPatch{
+		"profile":       &u.Profile,
+		"data":          &u.Data,
+		"preferences":   &u.Preferences,
+		"subscriptions": &u.Subscriptions,
+	}[name]
+	if *target == nil {
+		*target = FieldPatch{}
+	}
+	return *target
+}
+
+// Diff computes the patch turning before into after: changed fields of profile,
+// data, preferences and subscriptions are set, fields missing in after (or
+// emptied and omitted when encoded) are deleted, and isVerified / isActive are
+// set when they differ. Objects are compared field by field; arrays as a whole.
+func Diff(before, after Account) (*AccountPatch, error) {
+	beforeFields, err := toFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := toFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := NewAccountPatch()
+	for _, section := range []string{"profile", "data", "preferences", "subscriptions"} {
+		b, _ := beforeFields[section].(map[string]interface{})
+		a, _ := afterFields[section].(map[string]interface{})
+		diffObjects(patch, section, b, a)
+	}
+	if before.IsVerified != after.IsVerified {
+		patch.Set("isVerified", after.IsVerified)
+	}
+	if before.IsActive != after.IsActive {
+		patch.Set("isActive", after.IsActive)
+	}
+	return patch, nil
+}
+
+func diffObjects(patch *AccountPatch, prefix string, before, after map[string]interface{}) {
+	keys := map[string]bool{}
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		path := prefix + "." + key
+		b, inBefore := before[key]
+		a, inAfter := after[key]
+		switch {
+		case !inAfter:
+			patch.Delete(path)
+		case !inBefore:
+			patch.Set(path, a)
+		default:
+			bObject, bIsObject := b.(map[string]interface{})
+			aObject, aIsObject := a.(map[string]interface{})
+			if bIsObject && aIsObject {
+				diffObjects(patch, path, bObject, aObject)
+			} else if !reflect.DeepEqual(a, b) {
+				patch.Set(path, a)
+			}
+		}
+	}
+}
+
+func toFields(account Account) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(account)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	err = json.Unmarshal(encoded, &fields)
+	return fields, err
+}
+
+// PatchAccount applies patch to the account. When the patch appends to arrays,
+// the account is read first to get their current values.
+func (a *AccountsAPI) PatchAccount(UID string, patch *AccountPatch) error {
+	return a.PatchAccountContext(context.Background(), UID, patch)
+}
+
+// PatchAccountContext is like PatchAccount but the requests are bound to ctx
+func (a *AccountsAPI) PatchAccountContext(ctx context.Context, UID string, patch *AccountPatch) error {
+	if patch.Empty() {
+		return nil
+	}
+
+	var base interface{}
+	if patch.hasAppends() {
+		params := map[string]string{
+			"UID":     UID,
+			"include": "profile,data,preferences,subscriptions",
+		}
+		body, err := a.requestRaw(ctx, "accounts.getAccountInfo", params)
+		if err != nil {
+			return err
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return err
+		}
+		base = fields
+	}
+
+	update, err := patch.Update(base)
+	if err != nil {
+		return err
+	}
+	return a.UpdateAccountInfoContext(ctx, UID, update)
+}
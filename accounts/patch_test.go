@@ -0,0 +1,225 @@
+package accounts_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/gigyatest"
+)
+
+func TestAccountPatchUpdate(t *testing.T) {
+	base := map[string]interface{}{
+		"data": map[string]interface{}{"visited": []interface{}{"Andalucia"}, "team": "Crushers"},
+	}
+
+	tests := []struct {
+		name  string
+		patch *accounts.AccountPatch
+		// want is the data section sent, as JSON
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "sibling paths",
+			patch: accounts.NewAccountPatch().Set("data.a.b", 1).Set("data.a.c", 2),
+			want:  `{"a":{"b":1,"c":2}}`,
+		},
+		{
+			name:  "same path twice",
+			patch: accounts.NewAccountPatch().Set("data.a", 1).Set("data.a", 2),
+			want:  `{"a":2}`,
+		},
+		{
+			name:  "delete replaces the paths below",
+			patch: accounts.NewAccountPatch().Set("data.a.b", 1).Set("data.a.c.d", 2).Delete("data.a"),
+			want:  `{"a":null}`,
+		},
+		{
+			name:  "object replaces the paths below",
+			patch: accounts.NewAccountPatch().Set("data.a.b", 1).Set("data.a", map[string]interface{}{"c": 2}),
+			want:  `{"a":{"c":2}}`,
+		},
+		{
+			name:  "path merged into an object",
+			patch: accounts.NewAccountPatch().Set("data.a", map[string]interface{}{"b": 1}).Set("data.a.c", 2).Set("data.a.b", 3),
+			want:  `{"a":{"b":3,"c":2}}`,
+		},
+		{
+			name:  "path merged into a struct",
+			patch: accounts.NewAccountPatch().Set("data.favoriteTeam", accounts.NameSince{Name: "Crushers"}).Set("data.favoriteTeam.since", "2023"),
+			want:  `{"favoriteTeam":{"name":"Crushers","since":"2023"}}`,
+		},
+		{
+			name:    "path below a deleted path",
+			patch:   accounts.NewAccountPatch().Delete("data.a").Set("data.a.b", 1),
+			wantErr: true,
+		},
+		{
+			name:    "path below a value",
+			patch:   accounts.NewAccountPatch().Set("data.a", "x").Set("data.a.b", 1),
+			wantErr: true,
+		},
+		{
+			name:  "append to the base array",
+			patch: accounts.NewAccountPatch().Append("data.visited", "Madrid").Append("data.visited", "Paris"),
+			want:  `{"visited":["Andalucia","Madrid","Paris"]}`,
+		},
+		{
+			name:  "append to a missing array",
+			patch: accounts.NewAccountPatch().Append("data.events", "open"),
+			want:  `{"events":["open"]}`,
+		},
+		{
+			name:  "append after set",
+			patch: accounts.NewAccountPatch().Set("data.visited", []string{"Rome"}).Append("data.visited", "Madrid"),
+			want:  `{"visited":["Rome","Madrid"]}`,
+		},
+		{
+			name:  "append after delete",
+			patch: accounts.NewAccountPatch().Delete("data.visited").Append("data.visited", "Madrid"),
+			want:  `{"visited":["Madrid"]}`,
+		},
+		{
+			name:  "set after append",
+			patch: accounts.NewAccountPatch().Append("data.visited", "Madrid").Set("data.visited", []string{"Rome"}),
+			want:  `{"visited":["Rome"]}`,
+		},
+		{
+			name:  "delete after append below",
+			patch: accounts.NewAccountPatch().Append("data.trips.visited", "Madrid").Delete("data.trips"),
+			want:  `{"trips":null}`,
+		},
+		{
+			name:    "append to a value",
+			patch:   accounts.NewAccountPatch().Append("data.team", "Fireballs"),
+			wantErr: true,
+		},
+		{name: "section only", patch: accounts.NewAccountPatch().Set("data", map[string]interface{}{}), wantErr: true},
+		{name: "unsupported path", patch: accounts.NewAccountPatch().Set("identities.provider", "x"), wantErr: true},
+		{name: "isVerified not a bool", patch: accounts.NewAccountPatch().Set("isVerified", "yes"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update, err := tt.patch.Update(base)
+			if err == nil {
+				_, err = json.Marshal(update.Data)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Update succeeded with data %v, want an error", update.Data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+			got, _ := json.Marshal(update.Data)
+			if string(got) != tt.want {
+				t.Errorf("data = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccountPatchAppendNeedsBase(t *testing.T) {
+	if _, err := accounts.NewAccountPatch().Append("data.visited", "Madrid").Update(nil); err == nil {
+		t.Error("Append without a base succeeded")
+	}
+	update, err := accounts.NewAccountPatch().Set("isVerified", true).Set("username", "jane").Update(nil)
+	if err != nil || update.IsVerified == nil || !*update.IsVerified || update.Username == nil || *update.Username != "jane" {
+		t.Errorf("Update = %+v, %v", update, err)
+	}
+}
+
+// accountOf decodes an account written as JSON
+func accountOf(t *testing.T, encoded string) accounts.Account {
+	t.Helper()
+	var account accounts.Account
+	if err := json.Unmarshal([]byte(encoded), &account); err != nil {
+		t.Fatalf("invalid account %s: %v", encoded, err)
+	}
+	return account
+}
+
+func TestDiffRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+	}{
+		{
+			name:   "no change",
+			before: `{"UID":"uid-1","profile":{"firstName":"Jane"}}`,
+			after:  `{"UID":"uid-1","profile":{"firstName":"Jane"}}`,
+		},
+		{
+			name:   "mapped fields",
+			before: `{"UID":"uid-1","profile":{"firstName":"Jane","city":"Madrid"},"data":{"favoriteTeam":{"name":"Crushers","since":"2022"}}}`,
+			after:  `{"UID":"uid-1","profile":{"firstName":"Joan","country":"ES"},"data":{"favoriteTeam":{"name":"Fireballs","since":"2022"}},"isVerified":true}`,
+		},
+		{
+			name:   "unknown fields",
+			before: `{"UID":"uid-1","data":{"loyalty":{"tier":"gold","points":10},"tags":["a","b"]}}`,
+			after:  `{"UID":"uid-1","data":{"loyalty":{"tier":"platinum"},"tags":["b"],"survey":{"done":true}}}`,
+		},
+		{
+			name:   "removed objects and consents",
+			before: `{"UID":"uid-1","data":{"competition":{"name":"Open","when":"2024"}},"preferences":{"terms":{"ToS":{"isConsentGranted":true}}}}`,
+			after:  `{"UID":"uid-1","preferences":{"terms":{"ToS":{"isConsentGranted":false}},"livx":{"isConsentGranted":true}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, after := accountOf(t, tt.before), accountOf(t, tt.after)
+			patch, err := accounts.Diff(before, after)
+			if err != nil {
+				t.Fatalf("Diff: %v", err)
+			}
+
+			srv := gigyatest.NewServer()
+			defer srv.Close()
+			srv.AddAccount(before)
+			api := srv.AccountsAPI()
+			if err := api.PatchAccountContext(context.Background(), "uid-1", patch); err != nil {
+				t.Fatalf("PatchAccount(%v): %v", patch.Paths(), err)
+			}
+			if patch.Empty() && len(srv.Calls("accounts.setAccountInfo")) != 0 {
+				t.Error("empty patch sent a request")
+			}
+
+			stored, err := api.GetAccountInfoContext(context.Background(), "uid-1")
+			if err != nil {
+				t.Fatalf("GetAccountInfo: %v", err)
+			}
+			left, err := accounts.Diff(stored, after)
+			if err != nil {
+				t.Fatalf("Diff: %v", err)
+			}
+			if !left.Empty() {
+				t.Errorf("patch %v left changes %v", patch.Paths(), left.Paths())
+			}
+			if stored.IsVerified != after.IsVerified {
+				t.Errorf("isVerified = %v, want %v", stored.IsVerified, after.IsVerified)
+			}
+		})
+	}
+}
+
+func TestDiffPaths(t *testing.T) {
+	before := accountOf(t, `{"profile":{"firstName":"Jane","city":"Madrid"},"data":{"loyalty":{"tier":"gold","points":10}}}`)
+	after := accountOf(t, `{"profile":{"firstName":"Jane","country":"ES"},"data":{"loyalty":{"tier":"gold","points":12}},"isActive":true}`)
+
+	patch, err := accounts.Diff(before, after)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	want := []string{"data.loyalty.points", "isActive", "profile.city", "profile.country"}
+	if got := patch.Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff paths = %v, want %v", got, want)
+	}
+}
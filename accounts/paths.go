@@ -0,0 +1,275 @@
+package accounts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// lookupPath walks a decoded JSON object following a dotted path such as "profile.email"
+func lookupPath(fields map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = fields
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = object[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// setPath sets a dotted path in a decoded JSON object, creating the missing objects
+func setPath(fields map[string]interface{}, path string, value interface{}) error {
+	keys := strings.Split(path, ".")
+	object := fields
+	for i, key := range keys[:len(keys)-1] {
+		child, exists := object[key]
+		if !exists || child == nil {
+			next := map[string]interface{}{}
+			object[key] = next
+			object = next
+			continue
+		}
+		next, ok := child.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s is not an object", strings.Join(keys[:i+1], "."))
+		}
+		object = next
+	}
+	object[keys[len(keys)-1]] = value
+	return nil
+}
+
+// deletePath removes a dotted path from a decoded JSON object
+func deletePath(fields map[string]interface{}, path string) {
+	parent, last := fields, path
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		object, ok := lookupPath(fields, path[:i])
+		if parent, ok = object.(map[string]interface{}); !ok {
+			return
+		}
+		last = path[i+1:]
+	}
+	delete(parent, last)
+}
+
+// decodeFields decodes JSON into a map keeping numbers as json.Number
+func decodeFields(encoded []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	err := decoder.Decode(&fields)
+	return fields, err
+}
+
+/* ╭──────────────────────────────────────────╮ */
+/* │            ACCOUNT FIELD PATHS           │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// GetPath returns the value at a dotted path such as "data.favoriteTeam.name",
+// whether the field is mapped by the structs or kept in Extra.
+// Objects are returned as map[string]interface{} and numbers as json.Number.
+func (a Account) GetPath(path string) (interface{}, bool) {
+	fields, err := decodeFields([]byte(a.AsJSON()))
+	if err != nil {
+		return nil, false
+	}
+	return lookupPath(fields, path)
+}
+
+// GetString returns the string at path; false if it is missing or not a string
+func (a Account) GetString(path string) (string, bool) {
+	value, _ := a.GetPath(path)
+	s, ok := value.(string)
+	return s, ok
+}
+
+// GetBool returns the bool at path; false if it is missing or not a bool
+func (a Account) GetBool(path string) (bool, bool) {
+	value, _ := a.GetPath(path)
+	b, ok := value.(bool)
+	return b, ok
+}
+
+// GetInt returns the integer at path; false if it is missing or not an integer
+func (a Account) GetInt(path string) (int64, bool) {
+	value, _ := a.GetPath(path)
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	n, err := number.Int64()
+	return n, err == nil
+}
+
+// GetPathAs decodes the value at path into T:
+//
+//	team, err := accounts.GetPathAs[accounts.NameSince](account, "data.favoriteTeam")
+func GetPathAs[T any](a Account, path string) (T, error) {
+	var result T
+	value, ok := a.GetPath(path)
+	if !ok {
+		return result, fmt.Errorf("path %q not found", path)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return result, fmt.Errorf("path %q: %w", path, err)
+	}
+	return result, nil
+}
+
+// SetPath sets the value at a dotted path of profile, data or preferences.
+// Fields mapped by the structs are updated; any other field is kept as an
+// unknown field (see extra.go). An error is returned, and the account left
+// unchanged, when the account cannot hold the value (such as a string in a
+// bool field).
+func (a *Account) SetPath(path string, value interface{}) error {
+	before := *a
+	err := a.editSection(path, func(fields map[string]interface{}, field string) error {
+		return setPath(fields, field, value)
+	})
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	want, err := decodeValue(encoded)
+	if err != nil {
+		return err
+	}
+	got, found := a.GetPath(path)
+	if !holds(got, found, want) {
+		*a = before
+		return fmt.Errorf("account path %q cannot hold %s", path, encoded)
+	}
+	return nil
+}
+
+// decodeValue decodes any JSON value keeping numbers as json.Number
+func decodeValue(encoded []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	return value, err
+}
+
+// holds reports whether got (found or not) keeps the value want. The empty
+// values of want may be missing, as the structs omit them.
+func holds(got interface{}, found bool, want interface{}) bool {
+	if !found {
+		return isEmptyValue(want)
+	}
+	if object, ok := want.(map[string]interface{}); ok {
+		gotObject, ok := got.(map[string]interface{})
+		if !ok {
+			return len(object) == 0
+		}
+		for key, value := range object {
+			child, exists := gotObject[key]
+			if !holds(child, exists, value) {
+				return false
+			}
+		}
+		return true
+	}
+	gotEncoded, _ := json.Marshal(got)
+	wantEncoded, _ := json.Marshal(want)
+	return bytes.Equal(gotEncoded, wantEncoded) || (isEmptyValue(want) && isEmptyValue(got))
+}
+
+// isEmptyValue reports whether a decoded JSON value is null, false, 0, "" or empty
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// DeletePath removes the value at a dotted path of profile, data or preferences
+func (a *Account) DeletePath(path string) error {
+	return a.editSection(path, func(fields map[string]interface{}, field string) error {
+		deletePath(fields, field)
+		return nil
+	})
+}
+
+// editSection decodes the section of path into a map, edits it and decodes it back
+func (a *Account) editSection(path string, edit func(fields map[string]interface{}, field string) error) error {
+	section, field, _ := strings.Cut(path, ".")
+	if field == "" || !fieldPattern.MatchString(path) {
+		return fmt.Errorf("invalid account path %q", path)
+	}
+
+	var target interface{}
+	switch section {
+	case "profile":
+		target = &a.Profile
+	case "data":
+		target = &a.Data
+	case "preferences":
+		target = &a.Preferences
+	default:
+		return fmt.Errorf("account path %q must start with profile, data or preferences", path)
+	}
+
+	encoded, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+	fields, err := decodeFields(encoded)
+	if err != nil {
+		return err
+	}
+	if err := edit(fields, field); err != nil {
+		return fmt.Errorf("account path %q: %w", path, err)
+	}
+	if encoded, err = json.Marshal(fields); err != nil {
+		return err
+	}
+
+	switch section {
+	case "profile":
+		var profile Profile
+		if err = json.Unmarshal(encoded, &profile); err == nil {
+			a.Profile = profile
+		}
+	case "data":
+		var data Data
+		if err = json.Unmarshal(encoded, &data); err == nil {
+			a.Data = data
+		}
+	case "preferences":
+		var preferences Preferences
+		if err = json.Unmarshal(encoded, &preferences); err == nil {
+			a.Preferences = preferences
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("account path %q: %w", path, err)
+	}
+	return nil
+}
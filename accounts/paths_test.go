@@ -0,0 +1,233 @@
+package accounts_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gigya-module-go/accounts"
+)
+
+// pathAccount decodes the account used by the path tests
+func pathAccount(t *testing.T) accounts.Account {
+	t.Helper()
+	var account accounts.Account
+	err := json.Unmarshal([]byte(`{
+		"UID": "uid-1",
+		"profile": {"firstName": "Jane", "nickname": "JJ", "age": 42, "height": 1.7},
+		"data": {"favoriteTeam": {"name": "Crushers", "color": "purple"}, "loyalty": {"tier": "gold", "active": true}},
+		"preferences": {"terms": {"ToS": {"isConsentGranted": true}}}
+	}`), &account)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	return account
+}
+
+func TestGetPath(t *testing.T) {
+	account := pathAccount(t)
+
+	tests := []struct {
+		path  string
+		want  interface{}
+		found bool
+	}{
+		{path: "profile.firstName", want: "Jane", found: true},
+		{path: "profile.nickname", want: "JJ", found: true},
+		{path: "profile.age", want: json.Number("42"), found: true},
+		{path: "data.favoriteTeam.name", want: "Crushers", found: true},
+		{path: "data.favoriteTeam.color", want: "purple", found: true},
+		{path: "data.loyalty", want: map[string]interface{}{"tier": "gold", "active": true}, found: true},
+		{path: "preferences.terms.ToS.isConsentGranted", want: true, found: true},
+		{path: "preferences.livx"},
+		{path: "profile.lastName"},
+		{path: "data.missing.name"},
+		{path: "profile.firstName.first"},
+		{path: "data.loyalty.tier.name"},
+		{path: "profile."},
+		{path: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, found := account.GetPath(tt.path)
+			if found != tt.found || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetPath(%q) = %#v, %v, want %#v, %v", tt.path, got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestTypedGetters(t *testing.T) {
+	account := pathAccount(t)
+
+	if s, ok := account.GetString("profile.nickname"); !ok || s != "JJ" {
+		t.Errorf("GetString(profile.nickname) = %q, %v", s, ok)
+	}
+	if _, ok := account.GetString("profile.age"); ok {
+		t.Error("GetString of a number succeeded")
+	}
+	if n, ok := account.GetInt("profile.age"); !ok || n != 42 {
+		t.Errorf("GetInt(profile.age) = %d, %v", n, ok)
+	}
+	if _, ok := account.GetInt("profile.height"); ok {
+		t.Error("GetInt of 1.7 succeeded")
+	}
+	if _, ok := account.GetInt("profile.firstName"); ok {
+		t.Error("GetInt of a string succeeded")
+	}
+	if b, ok := account.GetBool("data.loyalty.active"); !ok || !b {
+		t.Errorf("GetBool(data.loyalty.active) = %v, %v", b, ok)
+	}
+	if _, ok := account.GetBool("data.loyalty.missing"); ok {
+		t.Error("GetBool of a missing path succeeded")
+	}
+
+	team, err := accounts.GetPathAs[accounts.NameSince](account, "data.favoriteTeam")
+	if err != nil || team.Name != "Crushers" {
+		t.Errorf("GetPathAs[NameSince](data.favoriteTeam) = %+v, %v", team, err)
+	}
+	if _, err := accounts.GetPathAs[accounts.NameSince](account, "data.missing"); err == nil {
+		t.Error("GetPathAs of a missing path succeeded")
+	}
+	if _, err := accounts.GetPathAs[int](account, "profile.firstName"); err == nil {
+		t.Error("GetPathAs[int] of a string succeeded")
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		value   interface{}
+		check   func(t *testing.T, account accounts.Account)
+		wantErr bool
+	}{
+		{
+			name:  "mapped field",
+			path:  "profile.firstName",
+			value: "Joan",
+			check: func(t *testing.T, account accounts.Account) {
+				if account.Profile.FirstName != "Joan" {
+					t.Errorf("FirstName = %q", account.Profile.FirstName)
+				}
+			},
+		},
+		{
+			name:  "unknown field",
+			path:  "data.loyalty.tier",
+			value: "platinum",
+			check: func(t *testing.T, account accounts.Account) {
+				if tier, _ := account.GetString("data.loyalty.tier"); tier != "platinum" {
+					t.Errorf("data.loyalty.tier = %q", tier)
+				}
+				if active, _ := account.GetBool("data.loyalty.active"); !active {
+					t.Error("data.loyalty.active lost")
+				}
+			},
+		},
+		{
+			name:  "missing objects are created",
+			path:  "data.survey.answers.first",
+			value: 3,
+			check: func(t *testing.T, account accounts.Account) {
+				if n, _ := account.GetInt("data.survey.answers.first"); n != 3 {
+					t.Errorf("data.survey.answers.first = %d", n)
+				}
+			},
+		},
+		{
+			name:  "nested unknown field of a mapped object",
+			path:  "data.favoriteTeam.color",
+			value: "gold",
+			check: func(t *testing.T, account accounts.Account) {
+				if color, _ := account.GetString("data.favoriteTeam.color"); color != "gold" || account.Data.FavoriteTeam.Name != "Crushers" {
+					t.Errorf("favoriteTeam = %+v, color %q", account.Data.FavoriteTeam, color)
+				}
+			},
+		},
+		{
+			name:  "consent",
+			path:  "preferences.livx.isConsentGranted",
+			value: true,
+			check: func(t *testing.T, account accounts.Account) {
+				if !account.Preferences.Livx.IsConsentGranted || !account.Preferences.Terms.ToS.IsConsentGranted {
+					t.Errorf("Preferences = %+v", account.Preferences)
+				}
+			},
+		},
+		{name: "wrong type for a mapped field", path: "profile.firstName", value: 5, wantErr: true},
+		{name: "wrong type for a consent", path: "preferences.terms.ToS.isConsentGranted", value: "yes", wantErr: true},
+		{name: "through a string", path: "profile.firstName.first", value: "J", wantErr: true},
+		{name: "through a mapped string", path: "data.favoriteTeam.name.short", value: "C", wantErr: true},
+		{name: "section only", path: "profile", value: "x", wantErr: true},
+		{name: "other section", path: "identities.provider", value: "x", wantErr: true},
+		{name: "invalid path", path: "profile..firstName", value: "x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := pathAccount(t)
+			before, _ := json.Marshal(account)
+
+			err := account.SetPath(tt.path, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("SetPath(%q, %v) succeeded, want an error", tt.path, tt.value)
+				}
+				if after, _ := json.Marshal(account); string(after) != string(before) {
+					t.Errorf("account changed by a failed SetPath:\n%s\nwas\n%s", after, before)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetPath(%q, %v): %v", tt.path, tt.value, err)
+			}
+			tt.check(t, account)
+			if nickname, _ := account.GetString("profile.nickname"); nickname != "JJ" {
+				t.Error("unknown profile field lost")
+			}
+		})
+	}
+}
+
+func TestDeletePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		gone    string
+		wantErr bool
+	}{
+		{path: "profile.firstName", gone: "profile.firstName"},
+		{path: "profile.nickname", gone: "profile.nickname"},
+		{path: "data.favoriteTeam.color", gone: "data.favoriteTeam.color"},
+		{path: "data.loyalty", gone: "data.loyalty.tier"},
+		{path: "preferences.terms.ToS", gone: "preferences.terms.ToS"},
+		{path: "data.missing.field"},
+		{path: "profile.firstName.first"},
+		{path: "UID.x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			account := pathAccount(t)
+			err := account.DeletePath(tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("DeletePath(%q) succeeded, want an error", tt.path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DeletePath(%q): %v", tt.path, err)
+			}
+			if tt.gone != "" {
+				if value, found := account.GetPath(tt.gone); found {
+					t.Errorf("%s = %v after DeletePath", tt.gone, value)
+				}
+			}
+			if name, _ := account.GetString("data.favoriteTeam.name"); name != "Crushers" {
+				t.Error("data.favoriteTeam.name lost")
+			}
+		})
+	}
+}
@@ -0,0 +1,373 @@
+package accounts
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │              QUERY BUILDER               │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// Query builds an accounts.search query. Values are always escaped, so user
+// input can be passed safely:
+//
+//	query, err := accounts.Select("UID", "profile.email").
+//		From("accounts").
+//		Where(accounts.Eq("data.idxImportId", importID), accounts.Gte("lastUpdatedTimestamp", since)).
+//		OrderBy("UID").
+//		Limit(100).
+//		Build()
+type Query struct {
+	fields  []string
+	from    string
+	where   []Condition
+	groupBy []string
+	orderBy []string
+	start   int
+	limit   int
+	err     error
+}
+
+// Condition is a filter of the where clause, built with Eq, Contains, And...
+type Condition interface {
+	render() (string, error)
+}
+
+var (
+	// fieldPattern matches field names such as "UID" or "data.favoriteTeam.name"
+	fieldPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$\-]*(\.[A-Za-z_$][A-Za-z0-9_$\-]*)*$`)
+	// aggregatePattern matches select expressions such as "count(*)" or "sum(data.points)"
+	aggregatePattern = regexp.MustCompile(`^(?i)(count|sum|min|max|avg)\((\*|[A-Za-z_$][A-Za-z0-9_$.\-]*)\)$`)
+	// limitPattern detects a limit clause at the end of a query
+	limitPattern = regexp.MustCompile(`(?i)\blimit\s+\d+\s*$`)
+)
+
+// Select starts a query returning the given fields ("*" when none is given)
+func Select(fields ...string) *Query {
+	q := &Query{from: "accounts"}
+	if len(fields) == 0 {
+		fields = []string{"*"}
+	}
+	for _, field := range fields {
+		if field != "*" && !fieldPattern.MatchString(field) && !aggregatePattern.MatchString(field) {
+			q.fail(fmt.Errorf("invalid select field %q", field))
+		}
+	}
+	q.fields = fields
+	return q
+}
+
+// From sets the collection to search ("accounts" by default)
+func (q *Query) From(collection string) *Query {
+	if !fieldPattern.MatchString(collection) {
+		q.fail(fmt.Errorf("invalid collection %q", collection))
+	}
+	q.from = collection
+	return q
+}
+
+// Where adds conditions to the where clause. Conditions are combined with AND.
+func (q *Query) Where(conditions ...Condition) *Query {
+	q.where = append(q.where, conditions...)
+	return q
+}
+
+// GroupBy adds group by fields
+func (q *Query) GroupBy(fields ...string) *Query {
+	for _, field := range fields {
+		q.checkField(field)
+	}
+	q.groupBy = append(q.groupBy, fields...)
+	return q
+}
+
+// OrderBy sorts the results by field, ascending
+func (q *Query) OrderBy(field string) *Query {
+	q.checkField(field)
+	q.orderBy = append(q.orderBy, field)
+	return q
+}
+
+// OrderByDesc sorts the results by field, descending
+func (q *Query) OrderByDesc(field string) *Query {
+	q.checkField(field)
+	q.orderBy = append(q.orderBy, field+" desc")
+	return q
+}
+
+// Start skips the first n results
+func (q *Query) Start(n int) *Query {
+	q.start = n
+	return q
+}
+
+// Limit caps the number of results
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+// Build renders the query, or returns the first error found while building it
+func (q *Query) Build() (string, error) {
+	if q.err != nil {
+		return "", q.err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "select %s from %s", strings.Join(q.fields, ", "), q.from)
+	if len(q.where) > 0 {
+		where, err := And(q.where...).render()
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(" where " + where)
+	}
+	if len(q.groupBy) > 0 {
+		b.WriteString(" group by " + strings.Join(q.groupBy, ", "))
+	}
+	if len(q.orderBy) > 0 {
+		b.WriteString(" order by " + strings.Join(q.orderBy, ", "))
+	}
+	if q.start > 0 {
+		fmt.Fprintf(&b, " start %d", q.start)
+	}
+	if q.limit > 0 {
+		fmt.Fprintf(&b, " limit %d", q.limit)
+	}
+	return b.String(), nil
+}
+
+// String renders the query for logging; use Build to get the error of an invalid query
+func (q *Query) String() string {
+	query, err := q.Build()
+	if err != nil {
+		return fmt.Sprintf("<invalid query: %v>", err)
+	}
+	return query
+}
+
+func (q *Query) checkField(field string) {
+	if !fieldPattern.MatchString(field) {
+		q.fail(fmt.Errorf("invalid field %q", field))
+	}
+}
+
+func (q *Query) fail(err error) {
+	if q.err == nil {
+		q.err = err
+	}
+}
+
+/* ╭──────────────────────────────────────────╮ */
+/* │                CONDITIONS                │ */
+/* ╰──────────────────────────────────────────╯ */
+
+type comparison struct {
+	field    string
+	operator string
+	value    interface{}
+}
+
+func (c comparison) render() (string, error) {
+	if !fieldPattern.MatchString(c.field) {
+		return "", fmt.Errorf("invalid field %q", c.field)
+	}
+	value, err := QuoteValue(c.value)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", c.field, err)
+	}
+	return fmt.Sprintf("%s %s %s", c.field, c.operator, value), nil
+}
+
+// Eq matches field = value. A nil value matches field is null.
+func Eq(field string, value interface{}) Condition {
+	if value == nil {
+		return IsNull(field)
+	}
+	return comparison{field, "=", value}
+}
+
+// NotEq matches field != value. A nil value matches field is not null.
+func NotEq(field string, value interface{}) Condition {
+	if value == nil {
+		return IsNotNull(field)
+	}
+	return comparison{field, "!=", value}
+}
+
+// Gt matches field > value
+func Gt(field string, value interface{}) Condition { return comparison{field, ">", value} }
+
+// Gte matches field >= value
+func Gte(field string, value interface{}) Condition { return comparison{field, ">=", value} }
+
+// Lt matches field < value
+func Lt(field string, value interface{}) Condition { return comparison{field, "<", value} }
+
+// Lte matches field <= value
+func Lte(field string, value interface{}) Condition { return comparison{field, "<=", value} }
+
+// Contains matches text fields containing value
+func Contains(field, value string) Condition { return comparison{field, "contains", value} }
+
+// NotContains matches text fields not containing value
+func NotContains(field, value string) Condition { return comparison{field, "not contains", value} }
+
+// Between matches low <= field <= high
+func Between(field string, low, high interface{}) Condition {
+	return And(Gte(field, low), Lte(field, high))
+}
+
+type inCondition struct {
+	field  string
+	values []interface{}
+}
+
+// In matches field equal to any of values
+func In(field string, values ...interface{}) Condition {
+	return inCondition{field, values}
+}
+
+func (c inCondition) render() (string, error) {
+	if !fieldPattern.MatchString(c.field) {
+		return "", fmt.Errorf("invalid field %q", c.field)
+	}
+	if len(c.values) == 0 {
+		return "", fmt.Errorf("%s: in needs at least one value", c.field)
+	}
+	quoted := make([]string, len(c.values))
+	for i, value := range c.values {
+		v, err := QuoteValue(value)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", c.field, err)
+		}
+		quoted[i] = v
+	}
+	return fmt.Sprintf("%s in (%s)", c.field, strings.Join(quoted, ", ")), nil
+}
+
+type nullCondition struct {
+	field string
+	not   bool
+}
+
+// IsNull matches missing or null fields
+func IsNull(field string) Condition { return nullCondition{field, false} }
+
+// IsNotNull matches fields with a value
+func IsNotNull(field string) Condition { return nullCondition{field, true} }
+
+func (c nullCondition) render() (string, error) {
+	if !fieldPattern.MatchString(c.field) {
+		return "", fmt.Errorf("invalid field %q", c.field)
+	}
+	if c.not {
+		return c.field + " is not null", nil
+	}
+	return c.field + " is null", nil
+}
+
+type logicalCondition struct {
+	operator   string
+	conditions []Condition
+}
+
+// And matches when every condition matches
+func And(conditions ...Condition) Condition { return logicalCondition{"and", conditions} }
+
+// Or matches when any condition matches
+func Or(conditions ...Condition) Condition { return logicalCondition{"or", conditions} }
+
+func (c logicalCondition) render() (string, error) {
+	if len(c.conditions) == 0 {
+		return "", fmt.Errorf("%s needs at least one condition", c.operator)
+	}
+	parts := make([]string, len(c.conditions))
+	for i, condition := range c.conditions {
+		part, err := condition.render()
+		if err != nil {
+			return "", err
+		}
+		if _, nested := condition.(logicalCondition); nested && len(c.conditions) > 1 {
+			part = "(" + part + ")"
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, " "+c.operator+" "), nil
+}
+
+type notCondition struct {
+	condition Condition
+}
+
+// Not negates condition
+func Not(condition Condition) Condition { return notCondition{condition} }
+
+func (c notCondition) render() (string, error) {
+	part, err := c.condition.render()
+	if err != nil {
+		return "", err
+	}
+	return "not (" + part + ")", nil
+}
+
+// QuoteValue renders value as a literal of the Gigya query language.
+// Strings are double quoted with backslash escaping; numbers and booleans are
+// written as is; time.Time values are quoted in RFC 3339 format.
+func QuoteValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return quoteString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float32:
+		return quoteFloat(float64(v), 32)
+	case float64:
+		return quoteFloat(v, 64)
+	case time.Time:
+		return quoteString(v.UTC().Format(time.RFC3339)), nil
+	case fmt.Stringer:
+		return quoteString(v.String()), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// quoteFloat writes f as a number; NaN and infinities have no literal in the query language
+func quoteFloat(f float64, bitSize int) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("unsupported value %v", f)
+	}
+	return strconv.FormatFloat(f, 'f', -1, bitSize), nil
+}
+
+// quoteString escapes backslashes, quotes and line breaks and wraps s in double quotes
+func quoteString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// withLimit appends a limit clause to query unless it already has one
+func withLimit(query string, limit int) string {
+	if limitPattern.MatchString(query) {
+		return query
+	}
+	return fmt.Sprintf("%s limit %d", query, limit)
+}
@@ -0,0 +1,182 @@
+package accounts_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/gigyatest"
+)
+
+type stringer struct{ s string }
+
+func (s stringer) String() string { return s.s }
+
+func TestQuoteValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "nil", value: nil, want: `null`},
+		{name: "plain string", value: "jane", want: `"jane"`},
+		{name: "empty string", value: "", want: `""`},
+		{name: "double quote", value: `say "hi"`, want: `"say \"hi\""`},
+		{name: "single quote", value: `O'Brien`, want: `"O'Brien"`},
+		{name: "backslash", value: `a\b`, want: `"a\\b"`},
+		{name: "trailing backslash", value: `a\`, want: `"a\\"`},
+		{name: "escaped quote", value: `\"`, want: `"\\\""`},
+		{name: "newline and carriage return", value: "a\nb\rc", want: `"a\nb\rc"`},
+		{name: "injection", value: `x" or UID != "`, want: `"x\" or UID != \""`},
+		{name: "bool", value: true, want: `true`},
+		{name: "int", value: -42, want: `-42`},
+		{name: "int64", value: int64(1700000000000), want: `1700000000000`},
+		{name: "uint", value: uint(7), want: `7`},
+		{name: "float64", value: 1.5, want: `1.5`},
+		{name: "float32", value: float32(0.25), want: `0.25`},
+		{name: "time", value: time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600)), want: `"2024-03-01T11:00:00Z"`},
+		{name: "stringer", value: stringer{`a"b`}, want: `"a\"b"`},
+		{name: "NaN", value: math.NaN(), wantErr: true},
+		{name: "infinity", value: math.Inf(1), wantErr: true},
+		{name: "unsupported type", value: []string{"a"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := accounts.QuoteValue(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("QuoteValue(%#v) = %q, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("QuoteValue(%#v): %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("QuoteValue(%#v) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryBuild(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *accounts.Query
+		want  string
+	}{
+		{
+			name:  "select all",
+			query: accounts.Select(),
+			want:  `select * from accounts`,
+		},
+		{
+			name: "conditions and clauses",
+			query: accounts.Select("UID", "profile.email").
+				Where(accounts.Eq("data.idxImportId", "42"), accounts.Gte("lastUpdatedTimestamp", 1700000000000)).
+				OrderBy("UID").
+				Limit(100),
+			want: `select UID, profile.email from accounts where data.idxImportId = "42" and lastUpdatedTimestamp >= 1700000000000 order by UID limit 100`,
+		},
+		{
+			name:  "aggregate",
+			query: accounts.Select("data.team", "count(*)").GroupBy("data.team"),
+			want:  `select data.team, count(*) from accounts group by data.team`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query.Build()
+			if err != nil {
+				t.Fatalf("Build: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Build() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryRejectsInvalidFields(t *testing.T) {
+	hostile := []string{
+		"",
+		"UID = 1 or 1",
+		"UID;drop",
+		`profile."email"`,
+		"profile..email",
+		".profile",
+		"profile.",
+		"1UID",
+		"UID from accounts where 1=1 --",
+	}
+
+	builders := map[string]func(field string) *accounts.Query{
+		"Select":  func(field string) *accounts.Query { return accounts.Select(field) },
+		"Where":   func(field string) *accounts.Query { return accounts.Select().Where(accounts.Eq(field, "x")) },
+		"In":      func(field string) *accounts.Query { return accounts.Select().Where(accounts.In(field, "x")) },
+		"IsNull":  func(field string) *accounts.Query { return accounts.Select().Where(accounts.IsNull(field)) },
+		"OrderBy": func(field string) *accounts.Query { return accounts.Select().OrderBy(field) },
+		"GroupBy": func(field string) *accounts.Query { return accounts.Select().GroupBy(field) },
+	}
+
+	for name, build := range builders {
+		for _, field := range hostile {
+			if name == "Select" && field == "" {
+				continue // no field selects "*"
+			}
+			t.Run(name+"/"+field, func(t *testing.T) {
+				query := build(field)
+				if got, err := query.Build(); err == nil {
+					t.Fatalf("Build() = %s, want an error", got)
+				}
+				if s := query.String(); !strings.HasPrefix(s, "<invalid query") {
+					t.Errorf("String() = %s, want an invalid query", s)
+				}
+			})
+		}
+	}
+}
+
+func TestQueryHostileValuesAgainstServer(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+
+	values := []string{
+		`plain@example.com`,
+		`quote"@example.com`,
+		`back\slash@example.com`,
+		`trailing\`,
+		`x" or UID != "`,
+		"new\nline",
+		`O'Brien`,
+	}
+	uids := map[string]string{}
+	for _, value := range values {
+		uids[value] = srv.AddAccount(map[string]interface{}{"profile": map[string]interface{}{"email": value}})
+	}
+	api := srv.AccountsAPI()
+
+	for _, value := range values {
+		t.Run(value, func(t *testing.T) {
+			query, err := accounts.Select("UID", "profile.email").Where(accounts.Eq("profile.email", value)).Build()
+			if err != nil {
+				t.Fatalf("Build: %v", err)
+			}
+			results, total, err := api.Search(query, 10)
+			if err != nil {
+				t.Fatalf("Search(%s): %v", query, err)
+			}
+			if total != 1 || len(results) != 1 {
+				t.Fatalf("Search(%s) matched %d accounts, want 1", query, total)
+			}
+			if results[0].UID != uids[value] {
+				t.Errorf("Search(%s) matched %s, want %s", query, results[0].UID, uids[value])
+			}
+		})
+	}
+}
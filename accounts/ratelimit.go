@@ -0,0 +1,159 @@
+package accounts
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │               RATE LIMITER               │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// MethodFamily groups the API methods that share a rate limit
+type MethodFamily string
+
+const (
+	FamilySearch MethodFamily = "search" // accounts.search
+	FamilyRead   MethodFamily = "read"   // accounts.get*
+	FamilyWrite  MethodFamily = "write"  // everything else (set, import, delete...)
+)
+
+// FamilyOf returns the family of an API method
+func FamilyOf(method string) MethodFamily {
+	switch {
+	case method == "accounts.search":
+		return FamilySearch
+	case strings.HasPrefix(method, "accounts.get"):
+		return FamilyRead
+	default:
+		return FamilyWrite
+	}
+}
+
+// RateLimit configures a token bucket: PerSecond tokens are added every second, up to Burst
+type RateLimit struct {
+	PerSecond float64
+	Burst     int
+}
+
+// RateLimiter throttles calls per method family with a token bucket.
+// It is safe for concurrent use: share one limiter between every goroutine
+// (and every AccountsAPI) that uses the same API key.
+type RateLimiter struct {
+	// Cooldown pauses a family after a rate limit error (403048) without Retry-After feedback
+	Cooldown time.Duration
+
+	mu      sync.Mutex
+	buckets map[MethodFamily]*bucket
+}
+
+// bucket is the state of a family. A family without a configured limit gets an
+// unthrottled bucket (zero Burst) when it is paused, so the pause still applies.
+type bucket struct {
+	limit       RateLimit
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// NewRateLimiter creates a limiter with a bucket per family.
+// Families missing from limits are not throttled.
+func NewRateLimiter(limits map[MethodFamily]RateLimit) *RateLimiter {
+	l := &RateLimiter{
+		Cooldown: time.Second,
+		buckets:  map[MethodFamily]*bucket{},
+	}
+	now := time.Now()
+	for family, limit := range limits {
+		if limit.Burst < 1 {
+			limit.Burst = 1
+		}
+		l.buckets[family] = &bucket{limit: limit, tokens: float64(limit.Burst), last: now}
+	}
+	return l
+}
+
+// WithRateLimiter throttles every call through limiter
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(a *AccountsAPI) {
+		a.rateLimiter = limiter
+	}
+}
+
+// Wait blocks until a call of method is allowed or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context, method string) error {
+	for {
+		delay, ok := l.reserve(FamilyOf(method), time.Now())
+		if ok {
+			return nil
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token if one is available, otherwise it returns how long to wait
+func (l *RateLimiter) reserve(family MethodFamily, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[family]
+	if !ok {
+		return 0, true
+	}
+	if now.Before(b.pausedUntil) {
+		return b.pausedUntil.Sub(now), false
+	}
+	if b.limit.Burst == 0 {
+		return 0, true
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.PerSecond
+	if b.tokens > float64(b.limit.Burst) {
+		b.tokens = float64(b.limit.Burst)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	if b.limit.PerSecond <= 0 {
+		return l.Cooldown, false
+	}
+	return time.Duration((1 - b.tokens) / b.limit.PerSecond * float64(time.Second)), false
+}
+
+// Pause stops every call of family for d, e.g. after Gigya asked to retry later.
+// A shorter pause never shortens one already in place. Families without a
+// limit are paused too, then stay unthrottled.
+func (l *RateLimiter) Pause(family MethodFamily, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[family]
+	if !ok {
+		b = &bucket{}
+		l.buckets[family] = b
+	}
+	until := time.Now().Add(d)
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+		b.tokens = 0
+	}
+}
+
+// feedback pauses the family of method when err is a rate limit error
+func (l *RateLimiter) feedback(method string, err error) {
+	if !IsRateLimited(err) {
+		return
+	}
+	d := l.Cooldown
+	if retryAfter := retryAfterOf(err); retryAfter > 0 {
+		d = retryAfter
+	}
+	l.Pause(FamilyOf(method), d)
+}
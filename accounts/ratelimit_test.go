@@ -0,0 +1,59 @@
+package accounts_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/gigyatest"
+)
+
+func TestRateLimiterPauseUnlimitedFamily(t *testing.T) {
+	limiter := accounts.NewRateLimiter(map[accounts.MethodFamily]accounts.RateLimit{
+		accounts.FamilySearch: {PerSecond: 100, Burst: 1},
+	})
+
+	// Not throttled before the pause
+	for i := 0; i < 5; i++ {
+		if err := limiter.Wait(context.Background(), "accounts.setAccountInfo"); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+
+	limiter.Pause(accounts.FamilyWrite, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, "accounts.setAccountInfo"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait during the pause = %v, want the deadline error", err)
+	}
+	if err := limiter.Wait(context.Background(), "accounts.getAccountInfo"); err != nil {
+		t.Errorf("Wait of another family: %v", err)
+	}
+}
+
+func TestRateLimitFeedback(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	uid := srv.AddAccount(map[string]interface{}{})
+
+	// Only searches are throttled: the rate limit error still pauses the reads
+	limiter := accounts.NewRateLimiter(map[accounts.MethodFamily]accounts.RateLimit{
+		accounts.FamilySearch: {PerSecond: 100, Burst: 1},
+	})
+	limiter.Cooldown = 200 * time.Millisecond
+	api := srv.AccountsAPI(accounts.WithRateLimiter(limiter), accounts.WithRetryPolicy(accounts.RetryPolicy{MaxAttempts: 1}))
+
+	srv.InjectError("accounts.getAccountInfo", accounts.ErrorCodeRateLimited, 1)
+	if _, err := api.GetAccountInfo(uid); !accounts.IsRateLimited(err) {
+		t.Fatalf("GetAccountInfo error = %v, want the rate limit error", err)
+	}
+	start := time.Now()
+	if _, err := api.GetAccountInfo(uid); err != nil {
+		t.Fatalf("GetAccountInfo: %v", err)
+	}
+	if waited := time.Since(start); waited < 150*time.Millisecond {
+		t.Errorf("GetAccountInfo after a rate limit error waited %v, want the cooldown", waited)
+	}
+}
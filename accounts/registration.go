@@ -0,0 +1,202 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │              REGISTRATION                │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// RegistrationStatus is the state of a registration after Register or FinalizeRegistration
+type RegistrationStatus string
+
+const (
+	// RegistrationCompleted: the account is registered and finalized
+	RegistrationCompleted RegistrationStatus = "completed"
+	// RegistrationPendingFinalization: registered with FinalizeRegistration false,
+	// call FinalizeRegistration with the regToken
+	RegistrationPendingFinalization RegistrationStatus = "pendingFinalization"
+	// RegistrationPendingRegistration: 206001, required fields are missing.
+	// Complete them with setAccountInfo and call FinalizeRegistration.
+	RegistrationPendingRegistration RegistrationStatus = "pendingRegistration"
+	// RegistrationPendingVerification: 206002, the email must be verified first
+	RegistrationPendingVerification RegistrationStatus = "pendingVerification"
+	// RegistrationValidationFailed: 400009, see ValidationErrors
+	RegistrationValidationFailed RegistrationStatus = "validationFailed"
+)
+
+// RegisterParams are the parameters of accounts.register.
+// Nil sections are not sent.
+type RegisterParams struct {
+	Email    string
+	Password string
+	Username string
+	// RegToken from InitRegistration. When empty, Register calls InitRegistration first.
+	RegToken      string
+	Profile       *Profile
+	Data          *Data
+	Preferences   *Preferences
+	Subscriptions map[string]Subscription
+	Lang          string
+	RegSource     string
+	// FinalizeRegistration completes the registration in the same call
+	FinalizeRegistration bool
+}
+
+// RegistrationResult is the outcome of Register or FinalizeRegistration
+type RegistrationResult struct {
+	UID              string
+	RegToken         string // Needed to continue a pending registration
+	Status           RegistrationStatus
+	ValidationErrors []ValidationError // Fields rejected by Gigya (RegistrationValidationFailed)
+}
+
+// Pending reports whether the registration needs another step
+func (r RegistrationResult) Pending() bool {
+	return r.Status == RegistrationPendingFinalization ||
+		r.Status == RegistrationPendingRegistration ||
+		r.Status == RegistrationPendingVerification
+}
+
+// InitRegistration starts a registration and returns its regToken
+// Parameters:
+// - isLite: true to register a lite account (email only)
+func (a *AccountsAPI) InitRegistration(isLite bool) (string, error) {
+	return a.InitRegistrationContext(context.Background(), isLite)
+}
+
+// InitRegistrationContext is like InitRegistration but the request is bound to ctx
+func (a *AccountsAPI) InitRegistrationContext(ctx context.Context, isLite bool) (string, error) {
+	params := map[string]string{
+		"isLite": strconv.FormatBool(isLite),
+	}
+
+	var response InitRegistrationResponse
+	if err := a.request(ctx, "accounts.initRegistration", params, &response); err != nil {
+		return "", err
+	}
+	return response.RegToken, nil
+}
+
+// Register registers a new account with accounts.register.
+// Pending states (206001, 206002) are not errors: they are reported in the
+// result Status together with the regToken needed to continue. A schema
+// validation failure (400009) returns the result with its ValidationErrors and
+// the *APIError. Any other failure returns the *APIError.
+func (a *AccountsAPI) Register(params RegisterParams) (RegistrationResult, error) {
+	return a.RegisterContext(context.Background(), params)
+}
+
+// RegisterContext is like Register but the requests are bound to ctx
+func (a *AccountsAPI) RegisterContext(ctx context.Context, params RegisterParams) (RegistrationResult, error) {
+	regToken := params.RegToken
+	if regToken == "" {
+		var err error
+		if regToken, err = a.InitRegistrationContext(ctx, false); err != nil {
+			return RegistrationResult{}, fmt.Errorf("initRegistration: %w", err)
+		}
+	}
+
+	values := map[string]string{
+		"regToken":             regToken,
+		"email":                params.Email,
+		"password":             params.Password,
+		"finalizeRegistration": strconv.FormatBool(params.FinalizeRegistration),
+	}
+	if params.Username != "" {
+		values["username"] = params.Username
+	}
+	if params.Lang != "" {
+		values["lang"] = params.Lang
+	}
+	if params.RegSource != "" {
+		values["regSource"] = params.RegSource
+	}
+	var err error
+	if params.Profile != nil {
+		err = errors.Join(err, setJSONParam(values, "profile", params.Profile))
+	}
+	if params.Data != nil {
+		err = errors.Join(err, setJSONParam(values, "data", params.Data))
+	}
+	if params.Preferences != nil {
+		err = errors.Join(err, setJSONParam(values, "preferences", params.Preferences))
+	}
+	if params.Subscriptions != nil {
+		err = errors.Join(err, setJSONParam(values, "subscriptions", params.Subscriptions))
+	}
+	if err != nil {
+		return RegistrationResult{}, err
+	}
+
+	var response RegisterResponse
+	err = a.request(ctx, "accounts.register", values, &response)
+	return registrationResult(response, regToken, params.FinalizeRegistration, err)
+}
+
+// FinalizeRegistration completes a pending registration
+// Parameters:
+// - regToken: The regToken returned by Register
+func (a *AccountsAPI) FinalizeRegistration(regToken string) (RegistrationResult, error) {
+	return a.FinalizeRegistrationContext(context.Background(), regToken)
+}
+
+// FinalizeRegistrationContext is like FinalizeRegistration but the request is bound to ctx
+func (a *AccountsAPI) FinalizeRegistrationContext(ctx context.Context, regToken string) (RegistrationResult, error) {
+	params := map[string]string{
+		"regToken": regToken,
+	}
+
+	var response RegisterResponse
+	err := a.request(ctx, "accounts.finalizeRegistration", params, &response)
+	return registrationResult(response, regToken, true, err)
+}
+
+// registrationResult maps the response and error of register / finalizeRegistration
+func registrationResult(response RegisterResponse, regToken string, finalized bool, err error) (RegistrationResult, error) {
+	result := RegistrationResult{UID: response.UID, RegToken: response.RegToken}
+	if result.RegToken == "" {
+		result.RegToken = regToken
+	}
+
+	if err == nil {
+		result.Status = RegistrationCompleted
+		if !finalized {
+			result.Status = RegistrationPendingFinalization
+		}
+		return result, nil
+	}
+
+	switch ErrorCode(err) {
+	case ErrorCodeAccountPendingRegistration:
+		result.Status = RegistrationPendingRegistration
+		return result, nil
+	case ErrorCodeAccountPendingVerification:
+		result.Status = RegistrationPendingVerification
+		return result, nil
+	case ErrorCodeValidation:
+		result.Status = RegistrationValidationFailed
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			result.ValidationErrors = apiErr.ValidationErrors
+		}
+		return result, passwordError(err)
+	default:
+		return result, err
+	}
+}
+
+// setJSONParam encodes value as the JSON parameter name
+func setJSONParam(params map[string]string, name string, value interface{}) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", name, err)
+	}
+	params[name] = string(encoded)
+	return nil
+}
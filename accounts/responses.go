@@ -47,8 +47,18 @@ type SearchResponse struct {
 	Results      Accounts `json:"results"`
 	ObjectsCount int      `json:"objectsCount"`
 	TotalCount   int      `json:"totalCount"`
-	NextCursor   string   `json:"nextCursor,omitempty"` // Cursor for paginated results
+	NextCursor   string   `json:"nextCursor,omitempty"`   // Cursor for paginated results
+	NextCursorID string   `json:"nextCursorId,omitempty"` // Cursor for paginated results, as named by CDC
 }
+
+// Cursor returns the cursor of the next page, whichever field carried it
+func (r SearchResponse) Cursor() string {
+	if r.NextCursorID != "" {
+		return r.NextCursorID
+	}
+	return r.NextCursor
+}
+
 type ImportFullAccountResponse struct {
 	CallID       string `json:"callId"`
 	ErrorCode    int    `json:"errorCode"`
@@ -88,3 +98,115 @@ type GetJWTPublicKeyResponse struct {
 	Use          string `json:"use"`
 	Kid          string `json:"kid"`
 }
+
+// GetJWTPublicKeysResponse is the JWKS returned by getJWTPublicKey with V2=true
+type GetJWTPublicKeysResponse struct {
+	CallID       string         `json:"callId"`
+	ErrorCode    int            `json:"errorCode"`
+	APIVersion   int            `json:"apiVersion"`
+	StatusCode   int            `json:"statusCode"`
+	StatusReason string         `json:"statusReason"`
+	Time         string         `json:"time"`
+	Keys         []JWTPublicKey `json:"keys"`
+}
+
+type GetJWTResponse struct {
+	CallID       string `json:"callId"`
+	ErrorCode    int    `json:"errorCode"`
+	APIVersion   int    `json:"apiVersion"`
+	StatusCode   int    `json:"statusCode"`
+	StatusReason string `json:"statusReason"`
+	Time         string `json:"time"`
+	IDToken      string `json:"id_token"`
+}
+
+// JWTPublicKey is a JSON Web Key (RSA)
+type JWTPublicKey struct {
+	Alg string `json:"alg"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+}
+
+type InitRegistrationResponse struct {
+	CallID       string `json:"callId"`
+	ErrorCode    int    `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+	ErrorDetails string `json:"errorDetails"`
+	APIVersion   int    `json:"apiVersion"`
+	StatusCode   int    `json:"statusCode"`
+	StatusReason string `json:"statusReason"`
+	Time         string `json:"time"`
+	RegToken     string `json:"regToken"`
+}
+
+// RegisterResponse is returned by accounts.register and accounts.finalizeRegistration.
+// On pending states (206001, 206002) the body still carries the UID and regToken.
+type RegisterResponse struct {
+	CallID           string            `json:"callId"`
+	ErrorCode        int               `json:"errorCode"`
+	ErrorMessage     string            `json:"errorMessage"`
+	ErrorDetails     string            `json:"errorDetails"`
+	APIVersion       int               `json:"apiVersion"`
+	StatusCode       int               `json:"statusCode"`
+	StatusReason     string            `json:"statusReason"`
+	Time             string            `json:"time"`
+	UID              string            `json:"UID"`
+	RegToken         string            `json:"regToken,omitempty"`
+	IsRegistered     bool              `json:"isRegistered,omitempty"`
+	IsVerified       bool              `json:"isVerified,omitempty"`
+	ValidationErrors []ValidationError `json:"validationErrors,omitempty"`
+}
+
+// LoginResponse is returned by accounts.login, accounts.notifyLogin and accounts.verifyLogin.
+// The account fields (UID, profile, data...) are inlined, as Gigya returns them.
+type LoginResponse struct {
+	CallID       string `json:"callId"`
+	ErrorCode    int    `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+	ErrorDetails string `json:"errorDetails"`
+	APIVersion   int    `json:"apiVersion"`
+	StatusCode   int    `json:"statusCode"`
+	StatusReason string `json:"statusReason"`
+	Time         string `json:"time"`
+	Account
+	SessionInfo SessionInfo `json:"sessionInfo"`
+	RegToken    string      `json:"regToken,omitempty"`
+}
+
+type GetConflictingAccountResponse struct {
+	CallID             string             `json:"callId"`
+	ErrorCode          int                `json:"errorCode"`
+	ErrorMessage       string             `json:"errorMessage"`
+	ErrorDetails       string             `json:"errorDetails"`
+	APIVersion         int                `json:"apiVersion"`
+	StatusCode         int                `json:"statusCode"`
+	StatusReason       string             `json:"statusReason"`
+	Time               string             `json:"time"`
+	ConflictingAccount ConflictingAccount `json:"conflictingAccount"`
+}
+
+type ResetPasswordResponse struct {
+	CallID             string `json:"callId"`
+	ErrorCode          int    `json:"errorCode"`
+	ErrorMessage       string `json:"errorMessage"`
+	ErrorDetails       string `json:"errorDetails"`
+	APIVersion         int    `json:"apiVersion"`
+	StatusCode         int    `json:"statusCode"`
+	StatusReason       string `json:"statusReason"`
+	Time               string `json:"time"`
+	UID                string `json:"UID,omitempty"`
+	PasswordResetToken string `json:"passwordResetToken,omitempty"`
+}
+
+type GetSchemaResponse struct {
+	CallID       string `json:"callId"`
+	ErrorCode    int    `json:"errorCode"`
+	APIVersion   int    `json:"apiVersion"`
+	StatusCode   int    `json:"statusCode"`
+	StatusReason string `json:"statusReason"`
+	Time         string `json:"time"`
+	Schema
+}
@@ -0,0 +1,127 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │                  RETRY                   │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// RetryPolicy controls how failed calls are retried.
+// The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after every attempt (2 doubles it)
+	Multiplier float64
+	// Jitter randomizes every delay by ±Jitter (0.2 = ±20%)
+	Jitter float64
+	// RetryableCodes are retried for idempotent calls (search, getAccountInfo...).
+	// Network errors are retried for idempotent calls too.
+	RetryableCodes []int
+	// SafeCodes are retried for every call, including non-idempotent ones
+	// (importFullAccount, setAccountInfo...). Only list codes for which Gigya
+	// guarantees the request was rejected before being processed.
+	SafeCodes []int
+	// OnRetry is called before sleeping for every retry
+	OnRetry func(RetryEvent)
+}
+
+// RetryEvent describes a failed attempt that is about to be retried
+type RetryEvent struct {
+	Method  string        // API method, e.g. "accounts.setAccountInfo"
+	Attempt int           // The attempt that failed, starting at 1
+	Delay   time.Duration // Time to wait before the next attempt
+	Err     error         // The error of the failed attempt
+}
+
+// DefaultRetryPolicy retries up to 4 attempts with exponential backoff from 500ms to 30s.
+// Rate limits (403048) are retried for every call; general server errors and
+// timeouts only for idempotent calls.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableCodes: []int{ErrorCodeRateLimited, ErrorCodeGeneralServerError, 504001, 504002},
+		SafeCodes:      []int{ErrorCodeRateLimited},
+	}
+}
+
+// WithRetryPolicy enables automatic retries of transient errors
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(a *AccountsAPI) {
+		a.retryPolicy = policy
+	}
+}
+
+// idempotentMethods can be sent again without side effects
+var idempotentMethods = map[string]bool{
+	"accounts.search":                true,
+	"accounts.getAccountInfo":        true,
+	"accounts.getJWTPublicKey":       true,
+	"accounts.getJWT":                true,
+	"accounts.getSchema":             true,
+	"accounts.getConflictingAccount": true,
+	"accounts.verifyLogin":           true,
+}
+
+// shouldRetry reports whether err is worth another attempt of method
+func (p RetryPolicy) shouldRetry(method string, err error) bool {
+	var canceled *CanceledError
+	if errors.As(err, &canceled) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if slices.Contains(p.SafeCodes, apiErr.ErrorCode) {
+			return true
+		}
+		return idempotentMethods[method] && slices.Contains(p.RetryableCodes, apiErr.ErrorCode)
+	}
+
+	// Network error: the request may have been processed
+	return idempotentMethods[method]
+}
+
+// backoff returns the delay to wait after the given failed attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
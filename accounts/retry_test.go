@@ -0,0 +1,50 @@
+package accounts_test
+
+import (
+	"testing"
+	"time"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/gigyatest"
+)
+
+func TestRetryIdempotentMethods(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	uid := srv.AddAccount(map[string]interface{}{"profile": map[string]interface{}{"email": "jane@example.com"}})
+	api := srv.AccountsAPI(accounts.WithRetryPolicy(accounts.RetryPolicy{
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+		RetryableCodes: []int{accounts.ErrorCodeGeneralServerError},
+	}))
+
+	tests := []struct {
+		method    string
+		call      func() error
+		wantCalls int
+	}{
+		{method: "accounts.search", call: func() error { _, _, err := api.Search("select * from accounts", 10); return err }, wantCalls: 2},
+		{method: "accounts.getAccountInfo", call: func() error { _, err := api.GetAccountInfo(uid); return err }, wantCalls: 2},
+		{method: "accounts.getJWTPublicKey", call: func() error { _, err := api.GetJWTPublicKey(); return err }, wantCalls: 2},
+		{method: "accounts.getJWT", call: func() error { _, err := api.GetJWT(uid, nil, time.Minute); return err }, wantCalls: 2},
+		{method: "accounts.getSchema", call: func() error { _, err := api.GetSchema(); return err }, wantCalls: 2},
+		{method: "accounts.getConflictingAccount", call: func() error { _, err := api.GetConflictingAccount("regToken"); return err }, wantCalls: 2},
+		{method: "accounts.verifyLogin", call: func() error { _, err := api.VerifyLogin(uid, ""); return err }, wantCalls: 2},
+		{method: "accounts.setAccountInfo", call: func() error { _, err := api.SetAccountInfo(accounts.Account{UID: uid}, false); return err }, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			before := len(srv.Calls(tt.method))
+			srv.InjectError(tt.method, accounts.ErrorCodeGeneralServerError, 1)
+			err := tt.call()
+			if got := len(srv.Calls(tt.method)) - before; got != tt.wantCalls {
+				t.Errorf("%d calls, want %d", got, tt.wantCalls)
+			}
+			if tt.wantCalls == 1 && accounts.ErrorCode(err) != accounts.ErrorCodeGeneralServerError {
+				t.Errorf("error = %v, want the server error", err)
+			}
+			srv.ClearErrors()
+		})
+	}
+}
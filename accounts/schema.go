@@ -0,0 +1,309 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │                  SCHEMA                  │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// Schema is the account schema of a site, as returned by accounts.getSchema.
+// A nil section is not read nor sent.
+type Schema struct {
+	Profile       *SchemaSection `json:"profileSchema,omitempty"`
+	Data          *SchemaSection `json:"dataSchema,omitempty"`
+	Subscriptions *SchemaSection `json:"subscriptionsSchema,omitempty"`
+	Preferences   *SchemaSection `json:"preferencesSchema,omitempty"`
+}
+
+// SchemaSection is the schema of an account section, keyed by dotted field name
+type SchemaSection struct {
+	Fields map[string]SchemaField `json:"fields"`
+	// DynamicSchema lets clients create fields not in the schema (data only)
+	DynamicSchema *bool `json:"dynamicSchema,omitempty"`
+}
+
+// SchemaField describes a field of the schema
+type SchemaField struct {
+	Type        string `json:"type,omitempty"`        // string, long, boolean, date, consent, subscription...
+	WriteAccess string `json:"writeAccess,omitempty"` // serverOnly, clientCreate or clientModify
+	Required    bool   `json:"required,omitempty"`
+	AllowNull   *bool  `json:"allowNull,omitempty"` // Gigya defaults to true
+	Encrypt     string `json:"encrypt,omitempty"`   // "AES" when encrypted
+	Format      string `json:"format,omitempty"`
+
+	// Extra holds the attributes not mapped above (arrayOp, languages...)
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+var schemaFieldFields = jsonFieldNames(reflect.TypeOf(SchemaField{}))
+
+// MarshalJSON encodes the field with its Extra attributes
+func (f SchemaField) MarshalJSON() ([]byte, error) {
+	type plain SchemaField
+	return marshalWithExtra(plain(f), f.Extra, nil)
+}
+
+// UnmarshalJSON decodes the field, keeping the unknown attributes in Extra
+func (f *SchemaField) UnmarshalJSON(b []byte) error {
+	type plain SchemaField
+	var decoded plain
+	extra, err := unmarshalWithExtra(b, &decoded, schemaFieldFields)
+	if err != nil {
+		return err
+	}
+	*f = SchemaField(decoded)
+	f.Extra = extra
+	return nil
+}
+
+// sections returns the sections by name, in a stable order
+func (s Schema) sections() []struct {
+	name    string
+	section *SchemaSection
+} {
+	return []struct {
+		name    string
+		section *SchemaSection
+	}{
+		{"profile", s.Profile},
+		{"data", s.Data},
+		{"subscriptions", s.Subscriptions},
+		{"preferences", s.Preferences},
+	}
+}
+
+// GetSchema gets the account schema of the site
+func (a *AccountsAPI) GetSchema() (Schema, error) {
+	return a.GetSchemaContext(context.Background())
+}
+
+// GetSchemaContext is like GetSchema but the request is bound to ctx
+func (a *AccountsAPI) GetSchemaContext(ctx context.Context) (Schema, error) {
+	var response GetSchemaResponse
+	if err := a.request(ctx, "accounts.getSchema", map[string]string{}, &response); err != nil {
+		return Schema{}, err
+	}
+	return response.Schema, nil
+}
+
+// SetSchema changes the account schema of the site. Only the non-nil sections
+// are sent; fields not in a section are left as they are.
+func (a *AccountsAPI) SetSchema(schema Schema) error {
+	return a.SetSchemaContext(context.Background(), schema)
+}
+
+// SetSchemaContext is like SetSchema but the request is bound to ctx
+func (a *AccountsAPI) SetSchemaContext(ctx context.Context, schema Schema) error {
+	params := map[string]string{}
+	for _, s := range schema.sections() {
+		if s.section == nil {
+			continue
+		}
+		if err := setJSONParam(params, s.name+"Schema", s.section); err != nil {
+			return err
+		}
+	}
+	if len(params) == 0 {
+		return fmt.Errorf("empty schema")
+	}
+
+	var response SetAccountInfoResponse
+	return a.request(ctx, "accounts.setSchema", params, &response)
+}
+
+// LoadSchemaFile reads a schema saved as JSON, such as a getSchema response
+// or a file written by SaveSchemaFile
+func LoadSchemaFile(path string) (Schema, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Schema{}, err
+	}
+	var schema Schema
+	if err := json.Unmarshal(content, &schema); err != nil {
+		return Schema{}, fmt.Errorf("invalid schema file %s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// SaveSchemaFile writes the schema as indented JSON, to check it in
+func SaveSchemaFile(path string, schema Schema) error {
+	content, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0o644)
+}
+
+/* ╭──────────────────────────────────────────╮ */
+/* │               SCHEMA DIFF                │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// SchemaChangeKind tells how a field changed
+type SchemaChangeKind string
+
+const (
+	SchemaFieldAdded   SchemaChangeKind = "added"
+	SchemaFieldRemoved SchemaChangeKind = "removed"
+	SchemaFieldChanged SchemaChangeKind = "changed"
+)
+
+// SchemaChange is a field that differs between two schemas
+type SchemaChange struct {
+	Section    string // profile, data, subscriptions or preferences
+	Field      string // Dotted field name; empty for a change of the section (dynamicSchema)
+	Kind       SchemaChangeKind
+	From       *SchemaField // nil when added
+	To         *SchemaField // nil when removed
+	Attributes []string     // Changed attributes (type, writeAccess...), when changed
+
+	// DynamicSchema is the new dynamicSchema, for a change of the section
+	DynamicSchema *bool
+}
+
+// String describes the change, e.g. "~ data.loyalty.tier: writeAccess"
+func (c SchemaChange) String() string {
+	name := c.Section
+	if c.Field != "" {
+		name += "." + c.Field
+	}
+	switch c.Kind {
+	case SchemaFieldAdded:
+		return fmt.Sprintf("+ %s (%s)", name, c.To.Type)
+	case SchemaFieldRemoved:
+		return fmt.Sprintf("- %s", name)
+	default:
+		return fmt.Sprintf("~ %s: %s", name, strings.Join(c.Attributes, ", "))
+	}
+}
+
+// SchemaDiff is the list of changes between two schemas, sorted by section and field
+type SchemaDiff []SchemaChange
+
+// String lists the changes, one per line
+func (d SchemaDiff) String() string {
+	lines := make([]string, len(d))
+	for i, change := range d {
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Patch returns the schema to send with SetSchema to apply the added and
+// changed fields. Removed fields are left out: the API cannot drop most fields.
+func (d SchemaDiff) Patch() Schema {
+	var patch Schema
+	for _, change := range d {
+		if change.Kind == SchemaFieldRemoved {
+			continue
+		}
+		target := map[string]**SchemaSection{
+			"profile":       &patch.Profile,
+			"data":          &patch.Data,
+			"subscriptions": &patch.Subscriptions,
+			"preferences":   &patch.Preferences,
+		}[change.Section]
+		if *target == nil {
+			*target = &SchemaSection{Fields: map[string]SchemaField{}}
+		}
+		if change.Field == "" {
+			(*target).DynamicSchema = change.DynamicSchema
+			continue
+		}
+		(*target).Fields[change.Field] = *change.To
+	}
+	return patch
+}
+
+// DiffSchemas compares two schemas, e.g. dev and prod, or a checked-in file
+// and a live site. A section nil in one schema only is compared as empty, so
+// its fields are reported as added or removed.
+func DiffSchemas(from, to Schema) SchemaDiff {
+	var diff SchemaDiff
+	toSections := to.sections()
+	for i, s := range from.sections() {
+		fromSection, toSection := s.section, toSections[i].section
+		switch {
+		case fromSection == nil && toSection == nil:
+			continue
+		case fromSection == nil:
+			fromSection = &SchemaSection{}
+		case toSection == nil:
+			toSection = &SchemaSection{DynamicSchema: fromSection.DynamicSchema}
+		}
+
+		if !reflect.DeepEqual(fromSection.DynamicSchema, toSection.DynamicSchema) {
+			diff = append(diff, SchemaChange{
+				Section:       s.name,
+				Kind:          SchemaFieldChanged,
+				Attributes:    []string{"dynamicSchema"},
+				DynamicSchema: toSection.DynamicSchema,
+			})
+		}
+
+		names := map[string]bool{}
+		for name := range fromSection.Fields {
+			names[name] = true
+		}
+		for name := range toSection.Fields {
+			names[name] = true
+		}
+		sorted := make([]string, 0, len(names))
+		for name := range names {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+
+		for _, name := range sorted {
+			fromField, inFrom := fromSection.Fields[name]
+			toField, inTo := toSection.Fields[name]
+			change := SchemaChange{Section: s.name, Field: name}
+			switch {
+			case !inTo:
+				change.Kind, change.From = SchemaFieldRemoved, &fromField
+			case !inFrom:
+				change.Kind, change.To = SchemaFieldAdded, &toField
+			default:
+				attributes := changedAttributes(fromField, toField)
+				if len(attributes) == 0 {
+					continue
+				}
+				change.Kind, change.From, change.To, change.Attributes = SchemaFieldChanged, &fromField, &toField, attributes
+			}
+			diff = append(diff, change)
+		}
+	}
+	return diff
+}
+
+// changedAttributes compares the fields attribute by attribute, as encoded
+func changedAttributes(from, to SchemaField) []string {
+	fromAttributes, toAttributes := schemaAttributes(from), schemaAttributes(to)
+	var changed []string
+	for name, value := range toAttributes {
+		if !reflect.DeepEqual(fromAttributes[name], value) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range fromAttributes {
+		if _, ok := toAttributes[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func schemaAttributes(field SchemaField) map[string]interface{} {
+	encoded, _ := json.Marshal(field)
+	var attributes map[string]interface{}
+	json.Unmarshal(encoded, &attributes)
+	return attributes
+}
@@ -0,0 +1,145 @@
+package accounts_test
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/gigyatest"
+)
+
+// schemaOf decodes a schema written as a getSchema response
+func schemaOf(t *testing.T, encoded string) accounts.Schema {
+	t.Helper()
+	var schema accounts.Schema
+	if err := json.Unmarshal([]byte(encoded), &schema); err != nil {
+		t.Fatalf("invalid schema %s: %v", encoded, err)
+	}
+	return schema
+}
+
+func TestDiffSchemas(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "identical",
+			from: `{"profileSchema":{"fields":{"city":{"type":"string"}}}}`,
+			to:   `{"profileSchema":{"fields":{"city":{"type":"string"}}}}`,
+			want: ``,
+		},
+		{
+			name: "added, removed and changed fields",
+			from: `{"dataSchema":{"fields":{"tier":{"type":"string","writeAccess":"serverOnly"},"old":{"type":"long"}}}}`,
+			to:   `{"dataSchema":{"fields":{"tier":{"type":"string","writeAccess":"clientModify","encrypt":"AES"},"points":{"type":"long"}}}}`,
+			want: "- data.old\n+ data.points (long)\n~ data.tier: encrypt, writeAccess",
+		},
+		{
+			name: "unknown attribute",
+			from: `{"dataSchema":{"fields":{"tags":{"type":"string","arrayOp":"push"}}}}`,
+			to:   `{"dataSchema":{"fields":{"tags":{"type":"string","arrayOp":"set"}}}}`,
+			want: "~ data.tags: arrayOp",
+		},
+		{
+			name: "dynamic schema",
+			from: `{"dataSchema":{"fields":{},"dynamicSchema":true}}`,
+			to:   `{"dataSchema":{"fields":{},"dynamicSchema":false}}`,
+			want: "~ data: dynamicSchema",
+		},
+		{
+			name: "section only in the target",
+			from: `{"profileSchema":{"fields":{}}}`,
+			to:   `{"profileSchema":{"fields":{}},"dataSchema":{"fields":{"tier":{"type":"string"},"points":{"type":"long"}}}}`,
+			want: "+ data.points (long)\n+ data.tier (string)",
+		},
+		{
+			name: "section only in the source",
+			from: `{"dataSchema":{"fields":{"tier":{"type":"string"}},"dynamicSchema":true}}`,
+			to:   `{}`,
+			want: "- data.tier",
+		},
+		{
+			name: "section missing in both",
+			from: `{"profileSchema":{"fields":{}}}`,
+			to:   `{"profileSchema":{"fields":{}}}`,
+			want: ``,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := accounts.DiffSchemas(schemaOf(t, tt.from), schemaOf(t, tt.to))
+			if got := diff.String(); got != tt.want {
+				t.Errorf("DiffSchemas =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaDiffPatch(t *testing.T) {
+	from := schemaOf(t, `{"profileSchema":{"fields":{"city":{"type":"string"}}},
+		"dataSchema":{"fields":{"tier":{"type":"string","writeAccess":"serverOnly"},"old":{"type":"long"}},"dynamicSchema":true}}`)
+	to := schemaOf(t, `{"profileSchema":{"fields":{"city":{"type":"string"}}},
+		"dataSchema":{"fields":{"tier":{"type":"string","writeAccess":"clientModify"}},"dynamicSchema":false},
+		"preferencesSchema":{"fields":{"terms.ToS":{"type":"consent","required":true}}}}`)
+
+	patch := accounts.DiffSchemas(from, to).Patch()
+	want := schemaOf(t, `{"dataSchema":{"fields":{"tier":{"type":"string","writeAccess":"clientModify"}},"dynamicSchema":false},
+		"preferencesSchema":{"fields":{"terms.ToS":{"type":"consent","required":true}}}}`)
+	if !reflect.DeepEqual(patch, want) {
+		got, _ := json.Marshal(patch)
+		wantJSON, _ := json.Marshal(want)
+		t.Errorf("Patch = %s, want %s", got, wantJSON)
+	}
+}
+
+func TestSchemaApplyDiff(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	api := srv.AccountsAPI()
+	ctx := context.Background()
+
+	live, err := api.GetSchemaContext(ctx)
+	if err != nil {
+		t.Fatalf("GetSchema: %v", err)
+	}
+	want := schemaOf(t, `{"dataSchema":{"fields":{"loyalty.tier":{"type":"string","writeAccess":"serverOnly"}},"dynamicSchema":false}}`)
+	want.Profile = live.Profile
+
+	diff := accounts.DiffSchemas(live, want)
+	if diff.String() != "~ data: dynamicSchema\n+ data.loyalty.tier (string)" {
+		t.Fatalf("DiffSchemas =\n%s", diff)
+	}
+	if err := api.SetSchemaContext(ctx, diff.Patch()); err != nil {
+		t.Fatalf("SetSchema: %v", err)
+	}
+
+	// Sections want does not have are still listed, as removed fields only
+	live, err = api.GetSchemaContext(ctx)
+	if err != nil {
+		t.Fatalf("GetSchema: %v", err)
+	}
+	for _, change := range accounts.DiffSchemas(live, want) {
+		if change.Kind != accounts.SchemaFieldRemoved || change.Section == "data" || change.Section == "profile" {
+			t.Errorf("change left after SetSchema: %s", change)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := accounts.SaveSchemaFile(path, live); err != nil {
+		t.Fatalf("SaveSchemaFile: %v", err)
+	}
+	saved, err := accounts.LoadSchemaFile(path)
+	if err != nil {
+		t.Fatalf("LoadSchemaFile: %v", err)
+	}
+	if diff := accounts.DiffSchemas(live, saved); len(diff) != 0 {
+		t.Errorf("schema changed by a save and load:\n%s", diff)
+	}
+}
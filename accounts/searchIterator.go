@@ -0,0 +1,189 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │            STREAMING SEARCH              │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// SearchScanner reads the results of a search one account at a time, fetching
+// the pages lazily with SearchWithCursor. Only the current page is kept in memory.
+//
+//	scanner := api.NewSearchScanner(ctx, "select * from accounts", 100)
+//	defer scanner.Close()
+//	for scanner.Next() {
+//		account := scanner.Account()
+//		...
+//	}
+//	if err := scanner.Err(); err != nil { ... }
+type SearchScanner struct {
+	scanner[Account]
+}
+
+// NewSearchScanner creates a scanner over the results of query.
+// No request is sent until the first call to Next.
+// Parameters:
+// - ctx: Context bound to every page request
+// - query: The search query to execute
+// - batchSize: The number of records to retrieve per request (max 100 recommended)
+func (a *AccountsAPI) NewSearchScanner(ctx context.Context, query string, batchSize int) *SearchScanner {
+	return &SearchScanner{newScanner(ctx, query, batchSize, a.searchPage)}
+}
+
+// Account returns the account read by the last call to Next
+func (s *SearchScanner) Account() Account {
+	return s.item
+}
+
+// SearchIter returns an iterator over every account matching query.
+// Pages are fetched lazily; breaking out of the loop stops the pagination.
+// A failure is yielded once as (Account{}, err) and ends the iteration.
+//
+//	for account, err := range api.SearchIter(ctx, query, 100) {
+//		if err != nil { ... }
+//	}
+func (a *AccountsAPI) SearchIter(ctx context.Context, query string, batchSize int) iter.Seq2[Account, error] {
+	return scanAll(ctx, query, batchSize, a.searchPage)
+}
+
+// searchPage fetches a page of accounts, the first one when cursor is empty
+func (a *AccountsAPI) searchPage(ctx context.Context, query string, limit int, cursor string) ([]Account, int, string, error) {
+	return a.SearchWithCursorContext(ctx, query, limit, cursor)
+}
+
+// pageFetcher fetches a page of search results, the first one when cursor is empty
+type pageFetcher[T any] func(ctx context.Context, query string, limit int, cursor string) ([]T, int, string, error)
+
+// scanner pages through the results of a search, decoded as T by fetchPage.
+// It backs SearchScanner, SearchIter and SearchIterAs.
+type scanner[T any] struct {
+	ctx       context.Context
+	query     string
+	batchSize int
+	fetchPage pageFetcher[T]
+
+	page []T
+	pos  int
+	item T
+
+	started bool
+	closed  bool
+	total   int
+	cursor  string // Cursor of the next page, empty when there are no more pages
+	fetched int
+	err     error
+}
+
+// newScanner creates a scanner; no request is sent until the first call to Next
+func newScanner[T any](ctx context.Context, query string, batchSize int, fetchPage pageFetcher[T]) scanner[T] {
+	if batchSize < 1 || batchSize > 100 {
+		batchSize = 100
+	}
+	return scanner[T]{
+		ctx:       ctx,
+		query:     query,
+		batchSize: batchSize,
+		fetchPage: fetchPage,
+	}
+}
+
+// Next advances to the next account, fetching a new page when needed.
+// It returns false at the end of the results, after an error or after Close.
+func (s *scanner[T]) Next() bool {
+	for s.pos >= len(s.page) {
+		if s.closed || s.err != nil || (s.started && s.cursor == "") {
+			return false
+		}
+		if !s.fetch() {
+			return false
+		}
+	}
+
+	s.item = s.page[s.pos]
+	s.pos++
+	s.fetched++
+	return true
+}
+
+// fetch loads the next page
+func (s *scanner[T]) fetch() bool {
+	var (
+		page   []T
+		total  int
+		cursor string
+		err    error
+	)
+	if !s.started {
+		page, total, cursor, err = s.fetchPage(s.ctx, s.query, s.batchSize, "")
+	} else {
+		page, _, cursor, err = s.fetchPage(s.ctx, s.query, s.batchSize, s.cursor)
+		total = s.total
+	}
+
+	if err != nil {
+		var canceled *CanceledError
+		if errors.As(err, &canceled) {
+			err = &CanceledError{Method: canceled.Method, Fetched: s.fetched, Total: s.total, Cursor: s.cursor, Err: canceled.Err}
+		}
+		s.err = err
+		return false
+	}
+
+	s.started = true
+	s.page, s.pos = page, 0
+	s.total, s.cursor = total, cursor
+	return true
+}
+
+// Err returns the error that stopped the scanner, if any
+func (s *scanner[T]) Err() error {
+	return s.err
+}
+
+// TotalCount returns the total number of accounts matching the query.
+// It is known once the first page has been fetched.
+func (s *scanner[T]) TotalCount() int {
+	return s.total
+}
+
+// Cursor returns the cursor of the next page to fetch, empty after the last page
+func (s *scanner[T]) Cursor() string {
+	return s.cursor
+}
+
+// Fetched returns the number of accounts returned by Next so far
+func (s *scanner[T]) Fetched() int {
+	return s.fetched
+}
+
+// Close stops the scanner and drops the current page and cursor.
+// CDC has no API to close a search cursor: an abandoned cursor simply expires
+// on the server side, so no further request is sent after Close.
+func (s *scanner[T]) Close() {
+	s.closed = true
+	s.page, s.pos = nil, 0
+	s.cursor = ""
+}
+
+// scanAll returns an iterator over the results of query, scanning them anew
+// for each iteration. A failure is yielded once with the zero T and ends the iteration.
+func scanAll[T any](ctx context.Context, query string, batchSize int, fetchPage pageFetcher[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		s := newScanner(ctx, query, batchSize, fetchPage)
+		defer s.Close()
+
+		for s.Next() {
+			if !yield(s.item, nil) {
+				return
+			}
+		}
+		if err := s.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
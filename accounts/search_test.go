@@ -0,0 +1,179 @@
+package accounts_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/gigyatest"
+)
+
+// newSearchServer returns a fake holding n accounts with UIDs uid-000, uid-001...
+func newSearchServer(t *testing.T, n int) *gigyatest.Server {
+	t.Helper()
+	srv := gigyatest.NewServer()
+	t.Cleanup(srv.Close)
+	for i := 0; i < n; i++ {
+		srv.AddAccount(accounts.Account{UID: fmt.Sprintf("uid-%03d", i), Profile: accounts.Profile{Email: fmt.Sprintf("user%d@example.com", i)}})
+	}
+	return srv
+}
+
+func TestSearchAllPaging(t *testing.T) {
+	tests := []struct {
+		name         string
+		accounts     int
+		batchSize    int
+		wantCalls    int
+		wantProgress []int
+	}{
+		{name: "no match", accounts: 0, batchSize: 10, wantCalls: 1, wantProgress: []int{0}},
+		{name: "single page", accounts: 7, batchSize: 10, wantCalls: 1, wantProgress: []int{7}},
+		{name: "exact pages", accounts: 20, batchSize: 10, wantCalls: 2, wantProgress: []int{10, 20}},
+		{name: "partial last page", accounts: 25, batchSize: 10, wantCalls: 3, wantProgress: []int{10, 20, 25}},
+		{name: "batch size capped", accounts: 150, batchSize: 500, wantCalls: 2, wantProgress: []int{100, 150}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSearchServer(t, tt.accounts)
+			api := srv.AccountsAPI()
+
+			var progress []int
+			results, total, err := api.SearchAllContext(context.Background(), "select * from accounts order by UID", tt.batchSize, func(fetched, total int) {
+				if total != tt.accounts {
+					t.Errorf("progress total = %d, want %d", total, tt.accounts)
+				}
+				progress = append(progress, fetched)
+			})
+			if err != nil {
+				t.Fatalf("SearchAllContext: %v", err)
+			}
+			if total != tt.accounts || len(results) != tt.accounts {
+				t.Fatalf("SearchAllContext returned %d accounts, total %d, want %d", len(results), total, tt.accounts)
+			}
+			for i, account := range results {
+				if want := fmt.Sprintf("uid-%03d", i); account.UID != want {
+					t.Fatalf("account %d = %s, want %s", i, account.UID, want)
+				}
+			}
+			if got := len(srv.Calls("accounts.search")); got != tt.wantCalls {
+				t.Errorf("accounts.search called %d times, want %d", got, tt.wantCalls)
+			}
+			if fmt.Sprint(progress) != fmt.Sprint(tt.wantProgress) {
+				t.Errorf("progress = %v, want %v", progress, tt.wantProgress)
+			}
+		})
+	}
+}
+
+func TestSearchAllCanceled(t *testing.T) {
+	srv := newSearchServer(t, 25)
+	api := srv.AccountsAPI()
+
+	tests := []struct {
+		name        string
+		cancelAfter int // cancel when this many accounts were fetched (-1 before the first page)
+		wantFetched int
+		wantCursor  bool
+	}{
+		{name: "before the first page", cancelAfter: -1, wantFetched: 0},
+		{name: "after the first page", cancelAfter: 10, wantFetched: 10, wantCursor: true},
+		{name: "after the second page", cancelAfter: 20, wantFetched: 20, wantCursor: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelAfter < 0 {
+				cancel()
+			}
+
+			results, _, err := api.SearchAllContext(ctx, "select * from accounts order by UID", 10, func(fetched, total int) {
+				if fetched == tt.cancelAfter {
+					cancel()
+				}
+			})
+
+			var canceled *accounts.CanceledError
+			if !errors.As(err, &canceled) {
+				t.Fatalf("SearchAllContext error = %v, want a *CanceledError", err)
+			}
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("errors.Is(%v, context.Canceled) = false", err)
+			}
+			if canceled.Method != "accounts.search" {
+				t.Errorf("Method = %q, want accounts.search", canceled.Method)
+			}
+			if canceled.Fetched != tt.wantFetched || len(results) != tt.wantFetched {
+				t.Errorf("Fetched = %d with %d accounts returned, want %d", canceled.Fetched, len(results), tt.wantFetched)
+			}
+			if (canceled.Cursor != "") != tt.wantCursor {
+				t.Errorf("Cursor = %q, want a cursor: %v", canceled.Cursor, tt.wantCursor)
+			}
+			if tt.wantCursor && canceled.Total != 25 {
+				t.Errorf("Total = %d, want 25", canceled.Total)
+			}
+		})
+	}
+}
+
+func TestSearchAllResumeFromCanceledCursor(t *testing.T) {
+	srv := newSearchServer(t, 25)
+	api := srv.AccountsAPI()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first, _, err := api.SearchAllContext(ctx, "select * from accounts order by UID", 10, func(fetched, total int) { cancel() })
+	var canceled *accounts.CanceledError
+	if !errors.As(err, &canceled) {
+		t.Fatalf("SearchAllContext error = %v, want a *CanceledError", err)
+	}
+
+	rest, _, next, err := api.SearchWithCursorContext(context.Background(), "", 10, canceled.Cursor)
+	if err != nil {
+		t.Fatalf("SearchWithCursorContext(%s): %v", canceled.Cursor, err)
+	}
+	if len(first) != 10 || len(rest) != 10 || next == "" {
+		t.Fatalf("got %d then %d accounts (next cursor %q), want 10 then 10 and a cursor", len(first), len(rest), next)
+	}
+	if rest[0].UID != "uid-010" {
+		t.Errorf("resumed at %s, want uid-010", rest[0].UID)
+	}
+}
+
+func TestSearchAllExpiredCursor(t *testing.T) {
+	srv := newSearchServer(t, 25)
+	api := srv.AccountsAPI()
+
+	results, _, err := api.SearchAllContext(context.Background(), "select * from accounts order by UID", 10, func(fetched, total int) {
+		srv.ExpireCursors()
+	})
+	if code := accounts.ErrorCode(err); code != gigyatest.ErrorCodeInvalidParameter {
+		t.Fatalf("SearchAllContext error = %v, want code %d", err, gigyatest.ErrorCodeInvalidParameter)
+	}
+	if len(results) != 10 {
+		t.Errorf("returned %d accounts, want the 10 fetched before the cursor expired", len(results))
+	}
+}
+
+func TestSearchAccountsForIdxImportId(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	srv.AddAccount(map[string]interface{}{"UID": "uid-1", "idxImportId": "import-42"})
+	srv.AddAccount(map[string]interface{}{"UID": "uid-2", "idxImportId": "import-7"})
+	srv.AddAccount(map[string]interface{}{"UID": "uid-3", "data": map[string]interface{}{"idxImportId": "import-42"}})
+
+	found, err := srv.AccountsAPI().SearchAccountsForIdxImportId("import-42")
+	if err != nil {
+		t.Fatalf("SearchAccountsForIdxImportId: %v", err)
+	}
+	if len(found) != 1 || found[0].UID != "uid-1" {
+		t.Errorf("found %+v, want uid-1 only", found)
+	}
+	if query := srv.Calls("accounts.search")[0].Params.Get("query"); query != `select * from accounts where idxImportId = "import-42"` {
+		t.Errorf("query = %s", query)
+	}
+}
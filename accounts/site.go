@@ -0,0 +1,147 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │            SITE SPECIFIC DATA            │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// AccountOf is an account whose data is decoded into a site specific struct D,
+// so each site defines its own data without touching the shared Data struct:
+//
+//	type OlympicsData struct {
+//		Utility *accounts.Utility `json:"utility,omitempty"`
+//	}
+//	results, total, err := accounts.SearchAs[OlympicsData](ctx, api, query, 100)
+//
+// The core fields (UID, Profile, Preferences...) are those of Account. Data
+// shadows Account.Data, which stays empty: read and write the site data through
+// Data, and encode the AccountOf itself (not the embedded Account).
+type AccountOf[D any] struct {
+	Account
+	Data D `json:"data"`
+}
+
+// AsJSON returns the account as JSON, with its site data
+func (a AccountOf[D]) AsJSON() string {
+	data, _ := json.Marshal(a)
+	return string(data)
+}
+
+type searchResponseOf[D any] struct {
+	Results      []AccountOf[D] `json:"results"`
+	TotalCount   int            `json:"totalCount"`
+	NextCursor   string         `json:"nextCursor,omitempty"`
+	NextCursorID string         `json:"nextCursorId,omitempty"`
+}
+
+// cursor returns the cursor of the next page, whichever field carried it
+func (r searchResponseOf[D]) cursor() string {
+	if r.NextCursorID != "" {
+		return r.NextCursorID
+	}
+	return r.NextCursor
+}
+
+// SearchAs runs a search and decodes the data of the accounts into D. It returns
+// a single page and opens no cursor: when totalCount is above the number of
+// accounts returned, use SearchAllAs or SearchIterAs to read them all.
+// Parameters:
+// - query: The search query to execute
+// - limit: The maximum number of results to return
+// Returns:
+// - accounts: The accounts matching the query
+// - totalCount: The total number of accounts matching the query
+func SearchAs[D any](ctx context.Context, a *AccountsAPI, query string, limit int) ([]AccountOf[D], int, error) {
+	if limit < 1 {
+		limit = 1
+	}
+	params := map[string]string{"query": withLimit(query, limit)}
+
+	var response searchResponseOf[D]
+	if err := a.request(ctx, "accounts.search", params, &response); err != nil {
+		return nil, 0, err
+	}
+	return response.Results, response.TotalCount, nil
+}
+
+// SearchWithCursorAs is like SearchWithCursor, decoding the data of the accounts into D
+// Returns:
+// - accounts: The accounts of the page
+// - totalCount: The total number of accounts matching the query
+// - nextCursor: Cursor of the next page, empty if no more results
+func SearchWithCursorAs[D any](ctx context.Context, a *AccountsAPI, query string, limit int, cursor string) ([]AccountOf[D], int, string, error) {
+	var response searchResponseOf[D]
+	if err := a.request(ctx, "accounts.search", searchParams(query, limit, cursor), &response); err != nil {
+		return nil, 0, "", err
+	}
+	return response.Results, response.TotalCount, response.cursor(), nil
+}
+
+// SearchAllAs is like SearchAllContext, decoding the data of the accounts into D.
+// On cancellation the accounts fetched so far are returned with a *CanceledError.
+func SearchAllAs[D any](ctx context.Context, a *AccountsAPI, query string, batchSize int, progressCallback func(fetched, total int)) ([]AccountOf[D], int, error) {
+	return searchAll(ctx, searchPageAs[D](a), query, batchSize, progressCallback)
+}
+
+// SearchIterAs is like SearchIter, decoding the data of the accounts into D.
+// Pages are fetched lazily; breaking out of the loop stops the pagination.
+// A failure is yielded once and ends the iteration.
+//
+//	for account, err := range accounts.SearchIterAs[AcmeData](ctx, api, query, 100) {
+//		if err != nil { ... }
+//	}
+func SearchIterAs[D any](ctx context.Context, a *AccountsAPI, query string, batchSize int) iter.Seq2[AccountOf[D], error] {
+	return scanAll(ctx, query, batchSize, searchPageAs[D](a))
+}
+
+// searchPageAs fetches the pages of a search with SearchWithCursorAs
+func searchPageAs[D any](a *AccountsAPI) pageFetcher[AccountOf[D]] {
+	return func(ctx context.Context, query string, limit int, cursor string) ([]AccountOf[D], int, string, error) {
+		return SearchWithCursorAs[D](ctx, a, query, limit, cursor)
+	}
+}
+
+// GetAccountInfoAs gets an account and decodes its data into D
+func GetAccountInfoAs[D any](ctx context.Context, a *AccountsAPI, UID string) (AccountOf[D], error) {
+	params := map[string]string{
+		"UID":     UID,
+		"include": "profile,data,preferences,subscriptions,emails,loginIDs",
+	}
+
+	var account AccountOf[D]
+	if err := a.request(ctx, "accounts.getAccountInfo", params, &account); err != nil {
+		return AccountOf[D]{}, err
+	}
+	return account, nil
+}
+
+// SetAccountInfoAs sends the data of the account, like SetAccountInfo. Fields of D
+// omitted when encoded (omitempty) are left untouched.
+func SetAccountInfoAs[D any](ctx context.Context, a *AccountsAPI, account AccountOf[D], isLite bool) error {
+	data, err := PatchFrom(account.Data)
+	if err != nil {
+		return err
+	}
+	return a.UpdateAccountInfoContext(ctx, account.UID, AccountUpdate{Data: data, IsLite: isLite})
+}
+
+// ImportFullAccountAs imports the account with its site data, like ImportFullAccount
+// Returns:
+// - UID: The UID of the imported account
+func ImportFullAccountAs[D any](ctx context.Context, a *AccountsAPI, account AccountOf[D]) (string, error) {
+	params := map[string]string{
+		"importPolicy": "insert",
+		"account":      account.AsJSON(),
+	}
+
+	var response ImportFullAccountResponse
+	if err := a.request(ctx, "accounts.importFullAccount", params, &response); err != nil {
+		return "", err
+	}
+	return response.UID, nil
+}
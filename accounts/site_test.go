@@ -0,0 +1,156 @@
+package accounts_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/gigyatest"
+)
+
+// siteData is the data of a site, decoded by the AccountOf functions
+type siteData struct {
+	Tier   string `json:"tier,omitempty"`
+	Points int    `json:"points,omitempty"`
+}
+
+// newSiteServer returns a fake holding n accounts uid-000, uid-001... with site data
+func newSiteServer(t *testing.T, n int) *gigyatest.Server {
+	t.Helper()
+	srv := gigyatest.NewServer()
+	t.Cleanup(srv.Close)
+	for i := 0; i < n; i++ {
+		srv.AddAccount(map[string]interface{}{
+			"UID":     fmt.Sprintf("uid-%03d", i),
+			"profile": map[string]interface{}{"email": fmt.Sprintf("user%d@example.com", i)},
+			"data":    map[string]interface{}{"tier": "gold", "points": i},
+		})
+	}
+	return srv
+}
+
+func TestSearchAllAs(t *testing.T) {
+	srv := newSiteServer(t, 25)
+
+	var progress []int
+	results, total, err := accounts.SearchAllAs[siteData](context.Background(), srv.AccountsAPI(), "select * from accounts", 10, func(fetched, total int) {
+		progress = append(progress, fetched)
+	})
+	if err != nil {
+		t.Fatalf("SearchAllAs: %v", err)
+	}
+	if total != 25 || len(results) != 25 {
+		t.Fatalf("SearchAllAs = %d results of %d, want 25 of 25", len(results), total)
+	}
+	for i, account := range results {
+		if account.UID != fmt.Sprintf("uid-%03d", i) || account.Data.Tier != "gold" || account.Data.Points != i {
+			t.Errorf("result %d = %s %+v", i, account.UID, account.Data)
+		}
+	}
+	if fmt.Sprint(progress) != "[10 20 25]" {
+		t.Errorf("progress = %v, want [10 20 25]", progress)
+	}
+	if calls := len(srv.Calls("accounts.search")); calls != 3 {
+		t.Errorf("searched %d times, want 3", calls)
+	}
+}
+
+func TestSearchAllAsCanceled(t *testing.T) {
+	srv := newSiteServer(t, 25)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results, _, err := accounts.SearchAllAs[siteData](ctx, srv.AccountsAPI(), "select * from accounts", 10, func(fetched, total int) {
+		cancel()
+	})
+	var canceled *accounts.CanceledError
+	if !errors.As(err, &canceled) {
+		t.Fatalf("SearchAllAs error = %v, want a *CanceledError", err)
+	}
+	if len(results) != 10 || canceled.Fetched != 10 || canceled.Total != 25 || canceled.Cursor == "" {
+		t.Errorf("canceled after %d results with %+v", len(results), canceled)
+	}
+}
+
+func TestSearchIterAs(t *testing.T) {
+	srv := newSiteServer(t, 25)
+	api := srv.AccountsAPI()
+
+	var UIDs []string
+	for account, err := range accounts.SearchIterAs[siteData](context.Background(), api, "select * from accounts", 10) {
+		if err != nil {
+			t.Fatalf("SearchIterAs: %v", err)
+		}
+		if account.Data.Tier != "gold" {
+			t.Errorf("%s data = %+v", account.UID, account.Data)
+		}
+		UIDs = append(UIDs, account.UID)
+	}
+	if len(UIDs) != 25 || UIDs[24] != "uid-024" {
+		t.Errorf("iterated %v", UIDs)
+	}
+
+	// Breaking out of the loop stops the pagination
+	calls := len(srv.Calls("accounts.search"))
+	for account := range accounts.SearchIterAs[siteData](context.Background(), api, "select * from accounts", 10) {
+		if account.UID == "uid-004" {
+			break
+		}
+	}
+	if got := len(srv.Calls("accounts.search")) - calls; got != 1 {
+		t.Errorf("break after the first page searched %d times, want 1", got)
+	}
+
+	// A failure is yielded once and ends the iteration
+	srv.InjectError("accounts.search", accounts.ErrorCodeGeneralServerError, 0)
+	failures := 0
+	for _, err := range accounts.SearchIterAs[siteData](context.Background(), srv.AccountsAPI(accounts.WithRetryPolicy(accounts.RetryPolicy{MaxAttempts: 1})), "select * from accounts", 10) {
+		if accounts.ErrorCode(err) != accounts.ErrorCodeGeneralServerError {
+			t.Errorf("SearchIterAs error = %v", err)
+		}
+		failures++
+	}
+	if failures != 1 {
+		t.Errorf("iteration yielded %d times, want the error once", failures)
+	}
+}
+
+func TestAccountInfoAs(t *testing.T) {
+	srv := newSiteServer(t, 2)
+	api := srv.AccountsAPI()
+	ctx := context.Background()
+
+	account, err := accounts.GetAccountInfoAs[siteData](ctx, api, "uid-001")
+	if err != nil {
+		t.Fatalf("GetAccountInfoAs: %v", err)
+	}
+	if account.Profile.Email != "user1@example.com" || account.Data.Tier != "gold" {
+		t.Errorf("GetAccountInfoAs = %+v", account)
+	}
+
+	// Points is omitted when zero, so it is left untouched
+	account.Data = siteData{Tier: "platinum"}
+	if err := accounts.SetAccountInfoAs(ctx, api, account, false); err != nil {
+		t.Fatalf("SetAccountInfoAs: %v", err)
+	}
+	stored, _ := srv.Account("uid-001")
+	data, _ := stored["data"].(map[string]interface{})
+	if data["tier"] != "platinum" || data["points"] != float64(1) {
+		t.Errorf("stored data = %v, want the new tier and the points untouched", data)
+	}
+
+	imported := accounts.AccountOf[siteData]{Account: accounts.Account{UID: "uid-new", Profile: accounts.Profile{Email: "new@example.com"}}, Data: siteData{Tier: "silver", Points: 7}}
+	UID, err := accounts.ImportFullAccountAs(ctx, api, imported)
+	if err != nil {
+		t.Fatalf("ImportFullAccountAs: %v", err)
+	}
+	got, err := accounts.GetAccountInfoAs[siteData](ctx, api, UID)
+	if err != nil {
+		t.Fatalf("GetAccountInfoAs of the imported account: %v", err)
+	}
+	if got.Data != imported.Data || got.Profile.Email != "new@example.com" {
+		t.Errorf("imported account = %+v", got)
+	}
+}
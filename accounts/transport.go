@@ -0,0 +1,266 @@
+package accounts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │                TRANSPORT                 │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// Option configures optional behaviour of an AccountsAPI
+type Option func(*AccountsAPI)
+
+// WithHTTPClient sets the *http.Client used for every request.
+// When not set, http.DefaultClient is used.
+func WithHTTPClient(client *http.Client) Option {
+	return func(a *AccountsAPI) {
+		a.httpClient = client
+	}
+}
+
+// WithSignedRequests makes every authenticated call use Gigya's HMAC-SHA1
+// request signing (timestamp + nonce + sig) instead of sending the secret.
+func WithSignedRequests() Option {
+	return func(a *AccountsAPI) {
+		a.signRequests = true
+	}
+}
+
+// request calls an authenticated API method and decodes the JSON body into response.
+// The body is decoded even when Gigya reports an error, so callers can read
+// the fields some error responses carry (regToken, validationErrors...).
+func (a *AccountsAPI) request(ctx context.Context, method string, params map[string]string, response interface{}) error {
+	body, err := a.requestRaw(ctx, method, params)
+	return decodeResponse(body, err, response)
+}
+
+// requestRaw calls an authenticated API method and returns the raw JSON body.
+// A non-nil body is returned together with the error when Gigya answered
+// with a non-zero errorCode.
+func (a *AccountsAPI) requestRaw(ctx context.Context, method string, params map[string]string) ([]byte, error) {
+	return a.send(ctx, method, func() (url.Values, error) {
+		values := url.Values{}
+		for key, value := range params {
+			values.Set(key, value)
+		}
+		values.Set("apiKey", a.apiKey)
+		values.Set("userKey", a.userKey)
+
+		if a.signRequests {
+			if err := a.sign(a.methodURL(method), values); err != nil {
+				return nil, err
+			}
+		} else {
+			values.Set("secret", a.secretKey)
+		}
+		return values, nil
+	})
+}
+
+// publicRequest calls an API method that only needs the apiKey (no credentials are sent)
+func (a *AccountsAPI) publicRequest(ctx context.Context, method string, params map[string]string, response interface{}) error {
+	body, err := a.send(ctx, method, func() (url.Values, error) {
+		values := url.Values{}
+		for key, value := range params {
+			values.Set(key, value)
+		}
+		values.Set("apiKey", a.apiKey)
+		return values, nil
+	})
+	return decodeResponse(body, err, response)
+}
+
+// send posts the values built by build, retrying according to the retry policy.
+// The values are built for every attempt, after waiting for the rate limiter,
+// so signed requests get a fresh timestamp and nonce however long the wait was.
+func (a *AccountsAPI) send(ctx context.Context, method string, build func() (url.Values, error)) ([]byte, error) {
+	policy := a.retryPolicy
+	for attempt := 1; ; attempt++ {
+		if a.rateLimiter != nil {
+			if ctxErr := a.rateLimiter.Wait(ctx, method); ctxErr != nil {
+				return nil, &CanceledError{Method: method, Err: ctxErr}
+			}
+		}
+
+		values, err := build()
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := a.post(ctx, method, values)
+		if a.rateLimiter != nil {
+			a.rateLimiter.feedback(method, err)
+		}
+		if err == nil || attempt >= policy.MaxAttempts || !policy.shouldRetry(method, err) {
+			return body, err
+		}
+
+		delay := policy.backoff(attempt)
+		if retryAfter := retryAfterOf(err); retryAfter > delay {
+			delay = retryAfter
+		}
+		if policy.OnRetry != nil {
+			policy.OnRetry(RetryEvent{Method: method, Attempt: attempt, Delay: delay, Err: err})
+		}
+		if ctxErr := sleepContext(ctx, delay); ctxErr != nil {
+			return nil, &CanceledError{Method: method, Err: ctxErr}
+		}
+	}
+}
+
+// decodeResponse unmarshals body into response, keeping the API error (if any) as the result
+func decodeResponse(body []byte, err error, response interface{}) error {
+	if body == nil || response == nil {
+		return err
+	}
+	if jsonErr := json.Unmarshal(body, response); jsonErr != nil && err == nil {
+		return jsonErr
+	}
+	return err
+}
+
+// post sends the form-encoded values to the API method and checks the response status
+func (a *AccountsAPI) post(ctx context.Context, method string, values url.Values) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &CanceledError{Method: method, Err: err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.methodURL(method), strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.client().Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, &CanceledError{Method: method, Err: ctxErr}
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every response carries the status fields, so it can be decoded as an APIError
+	status := &APIError{Method: method, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	if err := json.Unmarshal(body, status); err != nil {
+		if resp.StatusCode == http.StatusTooManyRequests {
+			status.ErrorCode = ErrorCodeRateLimited
+			status.StatusCode = resp.StatusCode
+			status.StatusReason = http.StatusText(resp.StatusCode)
+			return nil, status
+		}
+		return nil, fmt.Errorf("%s: invalid response (HTTP %d): %w", method, resp.StatusCode, err)
+	}
+	if status.ErrorCode != 0 {
+		return body, status
+	}
+
+	return body, nil
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func (a *AccountsAPI) client() *http.Client {
+	if a.httpClient != nil {
+		return a.httpClient
+	}
+	return http.DefaultClient
+}
+
+func (a *AccountsAPI) methodURL(method string) string {
+	return fmt.Sprintf("https://%s/%s", a.apiDomain, method)
+}
+
+// sign adds timestamp, nonce and sig to values. The secret is never sent.
+func (a *AccountsAPI) sign(requestURL string, values url.Values) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	values.Set("timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+	values.Set("nonce", hex.EncodeToString(nonce))
+
+	sig, err := CalcSignature(a.secretKey, http.MethodPost, requestURL, values)
+	if err != nil {
+		return err
+	}
+	values.Set("sig", sig)
+	return nil
+}
+
+// CalcSignature computes Gigya's HMAC-SHA1 request signature.
+// Parameters:
+// - secret: The base64 encoded secret (partner or user key secret)
+// - httpMethod: The HTTP method of the request (e.g. "POST")
+// - requestURL: The full URL of the API method, without query string
+// - params: The request parameters; "sig" is ignored if present
+// Returns:
+// - the base64 encoded signature to send in the "sig" parameter
+func CalcSignature(secret, httpMethod, requestURL string, params url.Values) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return "", fmt.Errorf("invalid secret: %w", err)
+	}
+
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return "", err
+	}
+	normalizedURL := strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + u.Path
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if k != "sig" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, percentEncode(k)+"="+percentEncode(params.Get(k)))
+	}
+
+	baseString := strings.ToUpper(httpMethod) + "&" + percentEncode(normalizedURL) + "&" + percentEncode(strings.Join(pairs, "&"))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write([]byte(baseString))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// percentEncode encodes s following RFC 3986, as Gigya expects in the signature base string
+func percentEncode(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
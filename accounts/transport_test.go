@@ -0,0 +1,125 @@
+package accounts_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/gigyatest"
+)
+
+func TestCalcSignature(t *testing.T) {
+	params := url.Values{"apiKey": {"key"}, "UID": {"a b+c/é"}, "timestamp": {"1700000000"}, "nonce": {"n"}}
+	base, err := accounts.CalcSignature(gigyatest.DefaultSecretKey, http.MethodPost, "https://accounts.eu1.gigya.com/accounts.getAccountInfo", params)
+	if err != nil {
+		t.Fatalf("CalcSignature: %v", err)
+	}
+
+	with := func(key, value string) url.Values {
+		changed := url.Values{}
+		for k, v := range params {
+			changed[k] = v
+		}
+		changed.Set(key, value)
+		return changed
+	}
+
+	tests := []struct {
+		name       string
+		secret     string
+		httpMethod string
+		requestURL string
+		params     url.Values
+		same       bool
+		wantErr    bool
+	}{
+		{name: "sig is ignored", params: with("sig", "anything"), same: true},
+		{name: "lower case method", httpMethod: "post", same: true},
+		{name: "upper case host", requestURL: "HTTPS://ACCOUNTS.EU1.GIGYA.COM/accounts.getAccountInfo", same: true},
+		{name: "other value", params: with("UID", "other")},
+		{name: "other parameter", params: with("extra", "1")},
+		{name: "other method", httpMethod: http.MethodGet},
+		{name: "other path", requestURL: "https://accounts.eu1.gigya.com/accounts.setAccountInfo"},
+		{name: "other secret", secret: "b3RoZXI="},
+		{name: "invalid secret", secret: "not base64!", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, httpMethod, requestURL, values := gigyatest.DefaultSecretKey, http.MethodPost, "https://accounts.eu1.gigya.com/accounts.getAccountInfo", params
+			if tt.secret != "" {
+				secret = tt.secret
+			}
+			if tt.httpMethod != "" {
+				httpMethod = tt.httpMethod
+			}
+			if tt.requestURL != "" {
+				requestURL = tt.requestURL
+			}
+			if tt.params != nil {
+				values = tt.params
+			}
+
+			sig, err := accounts.CalcSignature(secret, httpMethod, requestURL, values)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("CalcSignature = %q, want an error", sig)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CalcSignature: %v", err)
+			}
+			if (sig == base) != tt.same {
+				t.Errorf("CalcSignature = %q, base signature %q, want same = %v", sig, base, tt.same)
+			}
+		})
+	}
+}
+
+func TestSignedRequests(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	uid := srv.AddAccount(accounts.Account{Profile: accounts.Profile{Email: "jane@example.com"}})
+
+	tests := []struct {
+		name     string
+		secret   string
+		wantCode int
+	}{
+		{name: "valid secret", secret: srv.SecretKey},
+		{name: "wrong secret", secret: "d3Jvbmctc2VjcmV0", wantCode: gigyatest.ErrorCodeInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(srv.Calls("accounts.getAccountInfo"))
+			api := accounts.NewAccountsAPI(srv.APIKey, srv.UserKey, tt.secret, srv.Domain(),
+				accounts.WithHTTPClient(srv.Client()), accounts.WithSignedRequests())
+
+			account, err := api.GetAccountInfo(uid)
+			if code := accounts.ErrorCode(err); code != tt.wantCode {
+				t.Fatalf("GetAccountInfo error = %v, want code %d", err, tt.wantCode)
+			}
+			if tt.wantCode == 0 && account.UID != uid {
+				t.Errorf("GetAccountInfo UID = %q, want %q", account.UID, uid)
+			}
+
+			calls := srv.Calls("accounts.getAccountInfo")[before:]
+			if len(calls) == 0 {
+				t.Fatal("no request received")
+			}
+			for _, call := range calls {
+				if call.Params.Has("secret") {
+					t.Error("signed request sent the secret")
+				}
+				for _, param := range []string{"sig", "timestamp", "nonce"} {
+					if call.Params.Get(param) == "" {
+						t.Errorf("signed request without %s", param)
+					}
+				}
+			}
+		})
+	}
+}
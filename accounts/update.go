@@ -0,0 +1,244 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │             PARTIAL UPDATES              │ */
+/* ╰──────────────────────────────────────────╯ */
+
+type nullValue struct{}
+
+// MarshalJSON renders Null as JSON null
+func (nullValue) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// Null sets a field to null in a FieldPatch, which removes it in Gigya.
+// A nil value means the same; Null makes the intent explicit.
+var Null = nullValue{}
+
+// FieldPatch is a partial update of an account section (profile, data...),
+// keyed by dotted paths relative to the section:
+//
+//	accounts.FieldPatch{
+//		"favoriteTeam.name": "Crushers GC", // set
+//		"competition":       accounts.Null, // set to null
+//	}
+//
+// Paths not in the patch are left untouched.
+type FieldPatch map[string]interface{}
+
+// Set sets path to value and returns the patch, for chaining
+func (p FieldPatch) Set(path string, value interface{}) FieldPatch {
+	p[path] = value
+	return p
+}
+
+// SetNull sets path to null and returns the patch, for chaining
+func (p FieldPatch) SetNull(path string) FieldPatch {
+	p[path] = Null
+	return p
+}
+
+// literalField is a PatchFrom value whose key is a field name, not a dotted path
+type literalField struct {
+	value interface{}
+}
+
+// PatchFrom turns a section struct (Profile, Data...) into a patch of its
+// top-level fields, as they are encoded (fields omitted by omitempty are left untouched).
+// Its keys are field names: one that is not a valid path (such as "1st visit" or
+// "a.b", kept in Extra) is sent as is instead of being split on dots.
+func PatchFrom(section interface{}) (FieldPatch, error) {
+	encoded, err := json.Marshal(section)
+	if err != nil {
+		return nil, err
+	}
+	patch := FieldPatch{}
+	if err := json.Unmarshal(encoded, &patch); err != nil {
+		return nil, fmt.Errorf("%T does not encode to a JSON object", section)
+	}
+	for key, value := range patch {
+		if strings.Contains(key, ".") || !fieldPattern.MatchString(key) {
+			patch[key] = literalField{value}
+		}
+	}
+	return patch, nil
+}
+
+// MarshalJSON nests the dotted paths into JSON objects. A path below a path set
+// to an object (e.g. "a" set to {} and "a.b") is merged into it; below any
+// other value it is an error.
+func (p FieldPatch) MarshalJSON() ([]byte, error) {
+	paths := make([]string, 0, len(p))
+	for path := range p {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	root := map[string]interface{}{}
+	for _, path := range paths {
+		if literal, ok := p[path].(literalField); ok {
+			root[path] = literal.value
+			if literal.value == nil {
+				root[path] = Null
+			}
+			continue
+		}
+		if !fieldPattern.MatchString(path) {
+			return nil, fmt.Errorf("invalid patch path %q", path)
+		}
+		keys := strings.Split(path, ".")
+		object := root
+		for i, key := range keys[:len(keys)-1] {
+			child, exists := object[key]
+			if !exists {
+				next := map[string]interface{}{}
+				object[key] = next
+				object = next
+				continue
+			}
+			next, ok := child.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("patch path %q conflicts with %q", path, strings.Join(keys[:i+1], "."))
+			}
+			object = next
+		}
+
+		last := keys[len(keys)-1]
+		if _, exists := object[last]; exists {
+			return nil, fmt.Errorf("patch path %q conflicts with a nested path", path)
+		}
+		switch value := p[path].(type) {
+		case nil:
+			object[last] = Null
+		case map[string]interface{}:
+			object[last] = copyObject(value)
+		default:
+			object[last] = value
+		}
+	}
+	return json.Marshal(root)
+}
+
+// copyObject copies decoded JSON objects, so merging paths never changes the caller's maps
+func copyObject(object map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		if child, ok := value.(map[string]interface{}); ok {
+			value = copyObject(child)
+		}
+		copied[key] = value
+	}
+	return copied
+}
+
+// AccountUpdate is a partial update of an account with accounts.setAccountInfo.
+// Only the fields that are set are sent: nil patches and pointers are left out
+// (an empty, non-nil patch is sent as {}).
+type AccountUpdate struct {
+	Profile       FieldPatch
+	Data          FieldPatch
+	Preferences   FieldPatch
+	Subscriptions FieldPatch
+
+	IsVerified *bool
+	IsActive   *bool
+	IsLite     bool
+	Username   *string
+	Lang       *string
+
+	// AddLoginEmails and RemoveLoginEmails change the login identifiers
+	AddLoginEmails    []string
+	RemoveLoginEmails []string
+
+	// Params are sent as is, for parameters without a dedicated field
+	Params map[string]string
+}
+
+// Ptr returns a pointer to v, for the optional fields of AccountUpdate:
+//
+//	accounts.AccountUpdate{IsVerified: accounts.Ptr(true)}
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// params renders the update as setAccountInfo parameters
+func (u AccountUpdate) params() (map[string]string, error) {
+	params := map[string]string{}
+	for key, value := range u.Params {
+		params[key] = value
+	}
+
+	sections := []struct {
+		name  string
+		patch FieldPatch
+	}{
+		{"profile", u.Profile},
+		{"data", u.Data},
+		{"preferences", u.Preferences},
+		{"subscriptions", u.Subscriptions},
+	}
+	for _, section := range sections {
+		if section.patch == nil {
+			continue
+		}
+		if err := setJSONParam(params, section.name, section.patch); err != nil {
+			return nil, err
+		}
+	}
+
+	if u.IsVerified != nil {
+		params["isVerified"] = strconv.FormatBool(*u.IsVerified)
+	}
+	if u.IsActive != nil {
+		params["isActive"] = strconv.FormatBool(*u.IsActive)
+	}
+	if u.IsLite {
+		params["isLite"] = "true"
+	}
+	if u.Username != nil {
+		params["username"] = *u.Username
+	}
+	if u.Lang != nil {
+		params["lang"] = *u.Lang
+	}
+	if len(u.AddLoginEmails) > 0 {
+		params["addLoginEmails"] = strings.Join(u.AddLoginEmails, ",")
+	}
+	if len(u.RemoveLoginEmails) > 0 {
+		params["removeLoginEmails"] = strings.Join(u.RemoveLoginEmails, ",")
+	}
+
+	if len(params) == 0 {
+		return nil, fmt.Errorf("empty account update")
+	}
+	return params, nil
+}
+
+// UpdateAccountInfo applies a partial update to an account
+// Parameters:
+// - UID: The account to update
+// - update: The fields to change; fields not set are left untouched
+func (a *AccountsAPI) UpdateAccountInfo(UID string, update AccountUpdate) error {
+	return a.UpdateAccountInfoContext(context.Background(), UID, update)
+}
+
+// UpdateAccountInfoContext is like UpdateAccountInfo but the request is bound to ctx
+func (a *AccountsAPI) UpdateAccountInfoContext(ctx context.Context, UID string, update AccountUpdate) error {
+	params, err := update.params()
+	if err != nil {
+		return err
+	}
+	params["UID"] = UID
+
+	var response SetAccountInfoResponse
+	return a.request(ctx, "accounts.setAccountInfo", params, &response)
+}
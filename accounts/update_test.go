@@ -0,0 +1,110 @@
+package accounts_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/gigyatest"
+)
+
+func TestFieldPatchMarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   accounts.FieldPatch
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "dotted paths are nested",
+			patch: accounts.FieldPatch{"favoriteTeam.name": "Crushers", "favoriteTeam.since": "2023", "visited": "yes"},
+			want:  `{"favoriteTeam":{"name":"Crushers","since":"2023"},"visited":"yes"}`,
+		},
+		{
+			name:  "null",
+			patch: accounts.FieldPatch{"competition": accounts.Null, "events": nil},
+			want:  `{"competition":null,"events":null}`,
+		},
+		{
+			name:  "path merged into an object",
+			patch: accounts.FieldPatch{"competition": map[string]interface{}{"name": "Open"}, "competition.when": accounts.Null},
+			want:  `{"competition":{"name":"Open","when":null}}`,
+		},
+		{name: "path below a value", patch: accounts.FieldPatch{"visited": "yes", "visited.when": "now"}, wantErr: true},
+		{name: "key with a space", patch: accounts.FieldPatch{"my key": 1}, wantErr: true},
+		{name: "empty segment", patch: accounts.FieldPatch{"a..b": 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.patch)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Marshal = %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// literalData decodes data holding keys that are not valid dotted paths
+func literalData(t *testing.T) accounts.Data {
+	t.Helper()
+	var data accounts.Data
+	err := json.Unmarshal([]byte(`{"visited":"yes","1stVisit":"2023","favourite team":"Crushers","a.b":{"c":1}}`), &data)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	return data
+}
+
+func TestPatchFromLiteralKeys(t *testing.T) {
+	patch, err := accounts.PatchFrom(literalData(t))
+	if err != nil {
+		t.Fatalf("PatchFrom: %v", err)
+	}
+	patch.Set("favoriteTeam.name", "Fireballs")
+
+	encoded, err := json.Marshal(patch)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got, want map[string]interface{}
+	if err := json.Unmarshal(encoded, &got); err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal([]byte(`{"visited":"yes","1stVisit":"2023","favourite team":"Crushers","a.b":{"c":1},"favoriteTeam":{"name":"Fireballs"}}`), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("patch = %s", encoded)
+	}
+}
+
+func TestSetAccountInfoLiteralKeys(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	srv.AddAccount(accounts.Account{UID: "uid-1", Profile: accounts.Profile{Email: "jane@example.com"}})
+
+	account := accounts.Account{UID: "uid-1", Data: literalData(t)}
+	if _, err := srv.AccountsAPI().SetAccountInfo(account, false); err != nil {
+		t.Fatalf("SetAccountInfo: %v", err)
+	}
+
+	stored, _ := srv.Account("uid-1")
+	data, _ := stored["data"].(map[string]interface{})
+	for _, key := range []string{"1stVisit", "favourite team", "a.b"} {
+		if _, ok := data[key]; !ok {
+			t.Errorf("data[%q] not stored: %v", key, data)
+		}
+	}
+	if _, ok := data["a"]; ok {
+		t.Errorf("a.b was split into nested objects: %v", data)
+	}
+}
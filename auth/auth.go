@@ -0,0 +1,189 @@
+// Package auth authenticates HTTP requests with Gigya id_tokens. The
+// Authenticator holds the logic shared by Middleware (net/http) and the
+// framework adapters, such as ginauth.GigyaAuth.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gigya-module-go/jwt"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │              AUTHENTICATOR               │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// DefaultCookieName is the cookie read when the request has no Authorization header
+const DefaultCookieName = "id_token"
+
+var (
+	// ErrNoToken is returned when the request carries no id_token
+	ErrNoToken = errors.New("no id_token")
+	// ErrMissingField is returned when a required field of the token is missing or false
+	ErrMissingField = errors.New("missing required field")
+	// ErrUnscopedVerifier is returned by NewAuthenticator for a verifier without
+	// jwt.WithAPIKey or jwt.WithIssuer. The signing keys are shared by the sites
+	// of a data center, so such a verifier accepts the tokens of any of them.
+	ErrUnscopedVerifier = errors.New("verifier accepts the tokens of any site, set jwt.WithAPIKey or jwt.WithIssuer")
+)
+
+// Authenticator extracts the id_token of a request (Bearer header or cookie),
+// verifies it and checks the required fields
+type Authenticator struct {
+	verifier       *jwt.Verifier
+	cookieName     string
+	requiredFields []string
+	allowAnonymous bool
+}
+
+// Option configures an Authenticator
+type Option func(*Authenticator)
+
+// WithCookie sets the cookie holding the id_token (DefaultCookieName by
+// default); an empty name only reads the Authorization header
+func WithCookie(name string) Option {
+	return func(a *Authenticator) {
+		a.cookieName = name
+	}
+}
+
+// RequireFields rejects the tokens where any of the fields (claims such as
+// "isVerified" or "profile.email", dotted or nested) is missing, false or empty.
+// The token must be issued with those fields (GetJWT fields, or the site's
+// id_token settings).
+func RequireFields(fields ...string) Option {
+	return func(a *Authenticator) {
+		a.requiredFields = append(a.requiredFields, fields...)
+	}
+}
+
+// AllowAnonymous lets the requests without a token through, unauthenticated.
+// Requests with an invalid token are still rejected.
+func AllowAnonymous() Option {
+	return func(a *Authenticator) {
+		a.allowAnonymous = true
+	}
+}
+
+// NewAuthenticator creates an authenticator verifying the tokens with verifier.
+// Share the verifier between authenticators to share its key cache.
+// The verifier must only accept the tokens of the site (jwt.WithAPIKey or
+// jwt.WithIssuer), otherwise ErrUnscopedVerifier is returned.
+func NewAuthenticator(verifier *jwt.Verifier, opts ...Option) (*Authenticator, error) {
+	if verifier == nil {
+		return nil, errors.New("nil verifier")
+	}
+	if verifier.APIKey() == "" && verifier.Issuer() == "" {
+		return nil, ErrUnscopedVerifier
+	}
+
+	a := &Authenticator{verifier: verifier, cookieName: DefaultCookieName}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a, nil
+}
+
+// Authenticate verifies the id_token of r and returns its claims. With
+// AllowAnonymous, a request without token returns nil claims and no error.
+func (a *Authenticator) Authenticate(ctx context.Context, r *http.Request) (*jwt.GigyaIDTokenClaims, error) {
+	token, err := a.token(r)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		if a.allowAnonymous {
+			return nil, nil
+		}
+		return nil, ErrNoToken
+	}
+
+	claims, err := a.verifier.VerifyIDToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range a.requiredFields {
+		if !present(claimValue(claims.Raw, field)) {
+			return nil, fmt.Errorf("%w: %s", ErrMissingField, field)
+		}
+	}
+	return claims, nil
+}
+
+// token returns the Bearer token, or else the token cookie. An Authorization
+// header that is not a Bearer token is an error, not an anonymous request.
+func (a *Authenticator) token(r *http.Request) (string, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, _ := strings.Cut(header, " ")
+		token = strings.TrimSpace(token)
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return "", fmt.Errorf("%w: Authorization header is not a Bearer token", jwt.ErrMalformedToken)
+		}
+		return token, nil
+	}
+	if a.cookieName != "" {
+		if cookie, err := r.Cookie(a.cookieName); err == nil {
+			return cookie.Value, nil
+		}
+	}
+	return "", nil
+}
+
+// StatusCode returns the HTTP status to answer an Authenticate error with:
+// 403 for a missing required field, 401 for a missing or invalid token (or
+// Authorization header) and 503 when the keys could not be fetched
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrMissingField):
+		return http.StatusForbidden
+	case errors.Is(err, ErrNoToken),
+		errors.Is(err, jwt.ErrMalformedToken),
+		errors.Is(err, jwt.ErrUnsupportedAlgorithm),
+		errors.Is(err, jwt.ErrUnknownKey),
+		errors.Is(err, jwt.ErrInvalidSignature),
+		errors.Is(err, jwt.ErrMissingExpiry),
+		errors.Is(err, jwt.ErrTokenExpired),
+		errors.Is(err, jwt.ErrTokenNotYetValid),
+		errors.Is(err, jwt.ErrTokenTooOld),
+		errors.Is(err, jwt.ErrInvalidIssuer),
+		errors.Is(err, jwt.ErrInvalidAPIKey),
+		errors.Is(err, jwt.ErrMissingSubject):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusServiceUnavailable
+	}
+}
+
+// claimValue finds a claim by its dotted name, as a claim of its own or nested
+func claimValue(raw map[string]interface{}, field string) interface{} {
+	if value, ok := raw[field]; ok {
+		return value
+	}
+	var current interface{} = raw
+	for _, key := range strings.Split(field, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[key]
+	}
+	return current
+}
+
+// present reports whether a required claim is set: not null, false nor empty
+func present(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	default:
+		return true
+	}
+}
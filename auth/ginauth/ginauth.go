@@ -0,0 +1,87 @@
+// Package ginauth adapts auth.Authenticator to gin
+package ginauth
+
+import (
+	"gigya-module-go/auth"
+	"gigya-module-go/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Keys of the gin.Context values set by GigyaAuth
+const (
+	UIDKey    = "gigya.uid"
+	ClaimsKey = "gigya.claims"
+)
+
+// ErrorHandler answers a request rejected by GigyaAuth. It must abort the request.
+type ErrorHandler func(c *gin.Context, err error)
+
+// Option configures GigyaAuth
+type Option func(*config)
+
+type config struct {
+	errorHandler ErrorHandler
+}
+
+// WithErrorHandler replaces the default error response
+func WithErrorHandler(handler ErrorHandler) Option {
+	return func(c *config) {
+		c.errorHandler = handler
+	}
+}
+
+// defaultErrorHandler answers like auth.Middleware (auth.WriteError) and aborts
+func defaultErrorHandler(c *gin.Context, err error) {
+	auth.WriteError(c.Writer, err)
+	c.Abort()
+}
+
+// GigyaAuth returns a middleware authenticating the requests with authenticator,
+// as auth.Middleware does for net/http. The UID and claims of an authenticated
+// request are set in the gin.Context (read them with UID and Claims) and in the
+// request context (auth.ClaimsFromContext), for code shared with net/http
+// services; anonymous requests, when allowed, have neither.
+//
+//	verifier := jwt.NewVerifier(jwt.AccountsKeySource(api), jwt.WithAPIKey(apiKey))
+//	authenticator, err := auth.NewAuthenticator(verifier, auth.RequireFields("isVerified"))
+//	router.Use(ginauth.GigyaAuth(authenticator))
+func GigyaAuth(authenticator *auth.Authenticator, opts ...Option) gin.HandlerFunc {
+	cfg := config{errorHandler: defaultErrorHandler}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(c *gin.Context) {
+		claims, err := authenticator.Authenticate(c.Request.Context(), c.Request)
+		if err != nil {
+			cfg.errorHandler(c, err)
+			if !c.IsAborted() {
+				c.Abort()
+			}
+			return
+		}
+		if claims != nil {
+			c.Set(UIDKey, claims.UID())
+			c.Set(ClaimsKey, claims)
+			c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), claims))
+		}
+		c.Next()
+	}
+}
+
+// UID returns the UID of the authenticated request; false when anonymous
+func UID(c *gin.Context) (string, bool) {
+	UID := c.GetString(UIDKey)
+	return UID, UID != ""
+}
+
+// Claims returns the claims of the authenticated request; false when anonymous
+func Claims(c *gin.Context) (*jwt.GigyaIDTokenClaims, bool) {
+	value, ok := c.Get(ClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*jwt.GigyaIDTokenClaims)
+	return claims, ok
+}
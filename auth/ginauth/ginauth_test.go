@@ -0,0 +1,128 @@
+package ginauth_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gigya-module-go/auth"
+	"gigya-module-go/auth/ginauth"
+	"gigya-module-go/gigyatest"
+	"gigya-module-go/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRouter returns a router answering GET /me with the UID of the request,
+// or "anonymous", after checking the gin and request contexts agree
+func newRouter(t *testing.T, srv *gigyatest.Server, authOpts []auth.Option, opts ...ginauth.Option) *gin.Engine {
+	t.Helper()
+	authenticator, err := auth.NewAuthenticator(jwt.NewVerifier(jwt.AccountsKeySource(srv.AccountsAPI()), jwt.WithAPIKey(srv.APIKey)), authOpts...)
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ginauth.GigyaAuth(authenticator, opts...))
+	router.GET("/me", func(c *gin.Context) {
+		uid, ok := ginauth.UID(c)
+		claims, hasClaims := ginauth.Claims(c)
+		requestClaims, _ := auth.ClaimsFromContext(c.Request.Context())
+		if ok != hasClaims || claims != requestClaims || (ok && claims.UID() != uid) {
+			c.String(http.StatusInternalServerError, "UID %q and claims %v do not match", uid, claims)
+			return
+		}
+		if !ok {
+			uid = "anonymous"
+		}
+		c.String(http.StatusOK, uid)
+	})
+	return router
+}
+
+func TestGigyaAuth(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+
+	verified := srv.IDTokenClaims("uid-verified")
+	verified["isVerified"] = true
+	unverified := srv.IDTokenClaims("uid-unverified")
+	unverified["isVerified"] = false
+	otherSite := srv.IDTokenClaims("uid-verified")
+	otherSite["iss"], otherSite["apiKey"] = jwt.GigyaIssuer("other"), "other"
+
+	tests := []struct {
+		name          string
+		opts          []auth.Option
+		token         string
+		wantStatus    int
+		wantBody      string
+		wantChallenge string
+	}{
+		{name: "valid token", token: srv.IDToken(verified), wantStatus: http.StatusOK, wantBody: "uid-verified"},
+		{name: "no token", wantStatus: http.StatusUnauthorized, wantChallenge: "Bearer"},
+		{name: "invalid token", token: "not-a-token", wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer error="invalid_token"`},
+		{name: "token of another site", token: srv.IDToken(otherSite), wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer error="invalid_token"`},
+		{name: "required field", opts: []auth.Option{auth.RequireFields("isVerified")}, token: srv.IDToken(verified), wantStatus: http.StatusOK, wantBody: "uid-verified"},
+		{name: "required field false", opts: []auth.Option{auth.RequireFields("isVerified")}, token: srv.IDToken(unverified), wantStatus: http.StatusForbidden},
+		{name: "anonymous allowed", opts: []auth.Option{auth.AllowAnonymous()}, wantStatus: http.StatusOK, wantBody: "anonymous"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			newRouter(t, srv, tt.opts).ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %q)", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body, tt.wantBody)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+		})
+	}
+}
+
+func TestGigyaAuthErrorHandler(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+
+	// A handler that does not abort is aborted by GigyaAuth
+	var handled error
+	router := newRouter(t, srv, nil, ginauth.WithErrorHandler(func(c *gin.Context, err error) {
+		handled = err
+		c.Redirect(http.StatusFound, "/login")
+	}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me", nil))
+
+	if !errors.Is(handled, auth.ErrNoToken) {
+		t.Errorf("handled error = %v, want %v", handled, auth.ErrNoToken)
+	}
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/login" {
+		t.Errorf("status = %d, Location = %q, want the redirect of the handler", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestContextAccessors(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if _, ok := ginauth.UID(c); ok {
+		t.Error("UID of an anonymous context = true")
+	}
+	if _, ok := ginauth.Claims(c); ok {
+		t.Error("Claims of an anonymous context = true")
+	}
+	c.Set(ginauth.ClaimsKey, "not claims")
+	if _, ok := ginauth.Claims(c); ok {
+		t.Error("Claims of a value of another type = true")
+	}
+}
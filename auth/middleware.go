@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"gigya-module-go/jwt"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │            NET/HTTP MIDDLEWARE           │ */
+/* ╰──────────────────────────────────────────╯ */
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the claims of an authenticated request
+func NewContext(ctx context.Context, claims *jwt.GigyaIDTokenClaims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims set by the middleware; false when anonymous
+func ClaimsFromContext(ctx context.Context) (*jwt.GigyaIDTokenClaims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*jwt.GigyaIDTokenClaims)
+	return claims, ok && claims != nil
+}
+
+// UIDFromContext returns the UID of the authenticated request; false when anonymous
+func UIDFromContext(ctx context.Context) (string, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return claims.UID(), true
+}
+
+// ErrorHandler answers a request rejected by Middleware
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// MiddlewareOption configures Middleware
+type MiddlewareOption func(*middlewareConfig)
+
+type middlewareConfig struct {
+	errorHandler ErrorHandler
+}
+
+// WithErrorHandler replaces the default error response (WriteError)
+func WithErrorHandler(handler ErrorHandler) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.errorHandler = handler
+	}
+}
+
+// WriteError answers with StatusCode(err) and {"error": message}, adding a
+// WWW-Authenticate challenge to 401 responses. It is the default error response
+// of Middleware and of the framework adapters.
+func WriteError(w http.ResponseWriter, err error) {
+	status := StatusCode(err)
+	switch {
+	case errors.Is(err, ErrNoToken):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	case status == http.StatusUnauthorized:
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// Middleware returns net/http middleware authenticating the requests with
+// authenticator. The claims of an authenticated request are set in its context
+// (read them with ClaimsFromContext and UIDFromContext):
+//
+//	verifier := jwt.NewVerifier(jwt.AccountsKeySource(api), jwt.WithAPIKey(apiKey))
+//	authenticator, err := auth.NewAuthenticator(verifier)
+//	mux.Handle("/api/", auth.Middleware(authenticator)(apiHandler))
+func Middleware(authenticator *Authenticator, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	cfg := middlewareConfig{errorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+		WriteError(w, err)
+	}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := authenticator.Authenticate(r.Context(), r)
+			if err != nil {
+				cfg.errorHandler(w, r, err)
+				return
+			}
+			if claims != nil {
+				r = r.WithContext(NewContext(r.Context(), claims))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/auth"
+	"gigya-module-go/gigyatest"
+	"gigya-module-go/jwt"
+)
+
+// identity answers with the UID of the request, or "anonymous"
+var identity = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	uid, ok := auth.UIDFromContext(r.Context())
+	if !ok {
+		uid = "anonymous"
+	}
+	claims, _ := auth.ClaimsFromContext(r.Context())
+	if ok && (claims == nil || claims.UID() != uid) {
+		http.Error(w, "claims do not match the UID", http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(uid))
+})
+
+func TestMiddleware(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	api := srv.AccountsAPI()
+	verifier := jwt.NewVerifier(jwt.AccountsKeySource(api), jwt.WithAPIKey(srv.APIKey))
+
+	verified := srv.AddAccount(map[string]interface{}{"isVerified": true, "profile": map[string]interface{}{"email": "jane@example.com"}})
+	unverified := srv.AddAccount(map[string]interface{}{"isVerified": false})
+	issue := func(t *testing.T, uid string, fields ...string) string {
+		t.Helper()
+		token, err := api.GetJWT(uid, fields, time.Minute)
+		if err != nil {
+			t.Fatalf("GetJWT: %v", err)
+		}
+		return token
+	}
+	expired := srv.IDTokenClaims(verified)
+	expired["iat"], expired["exp"] = time.Now().Unix()-600, time.Now().Unix()-300
+	otherSite := srv.IDTokenClaims(verified)
+	otherSite["iss"], otherSite["apiKey"] = jwt.GigyaIssuer("other"), "other"
+
+	tests := []struct {
+		name          string
+		opts          []auth.Option
+		request       func(t *testing.T, r *http.Request)
+		wantStatus    int
+		wantBody      string
+		wantChallenge string
+	}{
+		{
+			name:       "bearer token",
+			request:    func(t *testing.T, r *http.Request) { r.Header.Set("Authorization", "Bearer "+issue(t, verified)) },
+			wantStatus: http.StatusOK,
+			wantBody:   verified,
+		},
+		{
+			name:       "lower case scheme",
+			request:    func(t *testing.T, r *http.Request) { r.Header.Set("Authorization", "bearer "+issue(t, verified)) },
+			wantStatus: http.StatusOK,
+			wantBody:   verified,
+		},
+		{
+			name: "cookie",
+			request: func(t *testing.T, r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: auth.DefaultCookieName, Value: issue(t, verified)})
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   verified,
+		},
+		{
+			name: "cookie disabled",
+			opts: []auth.Option{auth.WithCookie("")},
+			request: func(t *testing.T, r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: auth.DefaultCookieName, Value: issue(t, verified)})
+			},
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: "Bearer",
+		},
+		{
+			name:          "no token",
+			request:       func(t *testing.T, r *http.Request) {},
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: "Bearer",
+		},
+		{
+			name:       "anonymous allowed",
+			opts:       []auth.Option{auth.AllowAnonymous()},
+			request:    func(t *testing.T, r *http.Request) {},
+			wantStatus: http.StatusOK,
+			wantBody:   "anonymous",
+		},
+		{
+			name:          "basic credentials",
+			request:       func(t *testing.T, r *http.Request) { r.SetBasicAuth("jane", "secret") },
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer error="invalid_token"`,
+		},
+		{
+			name:          "basic credentials with anonymous allowed",
+			opts:          []auth.Option{auth.AllowAnonymous()},
+			request:       func(t *testing.T, r *http.Request) { r.SetBasicAuth("jane", "secret") },
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer error="invalid_token"`,
+		},
+		{
+			name:          "empty bearer with anonymous allowed",
+			opts:          []auth.Option{auth.AllowAnonymous()},
+			request:       func(t *testing.T, r *http.Request) { r.Header.Set("Authorization", "Bearer ") },
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer error="invalid_token"`,
+		},
+		{
+			name:          "garbage token",
+			request:       func(t *testing.T, r *http.Request) { r.Header.Set("Authorization", "Bearer not-a-token") },
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer error="invalid_token"`,
+		},
+		{
+			name: "expired token",
+			request: func(t *testing.T, r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+srv.IDToken(expired))
+			},
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer error="invalid_token"`,
+		},
+		{
+			name: "token of another site",
+			request: func(t *testing.T, r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+srv.IDToken(otherSite))
+			},
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer error="invalid_token"`,
+		},
+		{
+			name: "required fields present",
+			opts: []auth.Option{auth.RequireFields("isVerified", "profile.email")},
+			request: func(t *testing.T, r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+issue(t, verified, "isVerified", "profile.email"))
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   verified,
+		},
+		{
+			name: "required field false",
+			opts: []auth.Option{auth.RequireFields("isVerified")},
+			request: func(t *testing.T, r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+issue(t, unverified, "isVerified"))
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "required field not issued",
+			opts:       []auth.Option{auth.RequireFields("profile.email")},
+			request:    func(t *testing.T, r *http.Request) { r.Header.Set("Authorization", "Bearer "+issue(t, verified)) },
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator, err := auth.NewAuthenticator(verifier, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewAuthenticator: %v", err)
+			}
+			r := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+			tt.request(t, r)
+			w := httptest.NewRecorder()
+
+			auth.Middleware(authenticator)(identity).ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if w.Body.String() != tt.wantBody {
+					t.Errorf("body = %q, want %q", w.Body, tt.wantBody)
+				}
+				return
+			}
+			var body map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["error"] == "" {
+				t.Errorf("error body = %q, want {\"error\": message}", w.Body)
+			}
+		})
+	}
+}
+
+func TestMiddlewareKeysUnavailable(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	token := srv.IDToken(srv.IDTokenClaims("uid-1"))
+	srv.InjectError("accounts.getJWTPublicKey", accounts.ErrorCodeGeneralServerError, 0)
+	api := srv.AccountsAPI(accounts.WithRetryPolicy(accounts.RetryPolicy{MaxAttempts: 1}))
+	authenticator, err := auth.NewAuthenticator(jwt.NewVerifier(jwt.AccountsKeySource(api), jwt.WithAPIKey(srv.APIKey)))
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	auth.Middleware(authenticator)(identity).ServeHTTP(w, r)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if got := w.Header().Get("WWW-Authenticate"); got != "" {
+		t.Errorf("WWW-Authenticate = %q, want none", got)
+	}
+}
+
+func TestMiddlewareErrorHandler(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	authenticator, err := auth.NewAuthenticator(jwt.NewVerifier(jwt.AccountsKeySource(srv.AccountsAPI()), jwt.WithAPIKey(srv.APIKey)))
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+
+	var handled error
+	middleware := auth.Middleware(authenticator, auth.WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		handled = err
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}))
+	w := httptest.NewRecorder()
+	middleware(identity).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/me", nil))
+
+	if !errors.Is(handled, auth.ErrNoToken) {
+		t.Errorf("handled error = %v, want %v", handled, auth.ErrNoToken)
+	}
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/login" {
+		t.Errorf("status = %d, Location = %q, want the redirect of the handler", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	source := jwt.AccountsKeySource(srv.AccountsAPI())
+
+	tests := []struct {
+		name     string
+		verifier *jwt.Verifier
+		wantErr  error
+	}{
+		{name: "apiKey", verifier: jwt.NewVerifier(source, jwt.WithAPIKey(srv.APIKey))},
+		{name: "issuer", verifier: jwt.NewVerifier(source, jwt.WithIssuer(jwt.GigyaIssuer(srv.APIKey)))},
+		{name: "unscoped", verifier: jwt.NewVerifier(source), wantErr: auth.ErrUnscopedVerifier},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator, err := auth.NewAuthenticator(tt.verifier)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewAuthenticator error = %v, want %v", err, tt.wantErr)
+			}
+			if (authenticator == nil) != (tt.wantErr != nil) {
+				t.Errorf("NewAuthenticator = %v with error %v", authenticator, err)
+			}
+		})
+	}
+
+	if _, err := auth.NewAuthenticator(nil); err == nil {
+		t.Error("NewAuthenticator(nil) succeeded, want an error")
+	}
+}
+
+func TestContextAccessors(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := auth.ClaimsFromContext(r.Context()); ok {
+		t.Error("ClaimsFromContext of an empty context = true")
+	}
+	if _, ok := auth.UIDFromContext(auth.NewContext(r.Context(), nil)); ok {
+		t.Error("UIDFromContext with nil claims = true")
+	}
+
+	claims := &jwt.GigyaIDTokenClaims{Claims: jwt.Claims{Subject: "uid-1"}}
+	ctx := auth.NewContext(r.Context(), claims)
+	if got, ok := auth.ClaimsFromContext(ctx); !ok || got != claims {
+		t.Errorf("ClaimsFromContext = %v, %v, want the claims set", got, ok)
+	}
+	if uid, ok := auth.UIDFromContext(ctx); !ok || uid != "uid-1" {
+		t.Errorf("UIDFromContext = %q, %v, want uid-1", uid, ok)
+	}
+}
@@ -0,0 +1,66 @@
+package gigya
+
+import "gigya-module-go/accounts"
+
+// APIError is the error returned by every API call when Gigya answers with a
+// non-zero errorCode. Use errors.As to inspect it:
+//
+//	var apiErr *gigya.APIError
+//	if errors.As(err, &apiErr) && apiErr.ErrorCode == 403005 { ... }
+type APIError = accounts.APIError
+
+// PasswordPolicyError is returned when Gigya rejects a new password
+type PasswordPolicyError = accounts.PasswordPolicyError
+
+// ValidationError describes a single field rejected by Gigya
+type ValidationError = accounts.ValidationError
+
+// ErrorCode returns the Gigya errorCode carried by err, or 0 if err is not an *APIError
+func ErrorCode(err error) int {
+	return accounts.ErrorCode(err)
+}
+
+// IsNotFound reports whether err is a 403005 (unknown UID / account not found) error
+func IsNotFound(err error) bool {
+	return accounts.IsNotFound(err)
+}
+
+// IsRateLimited reports whether err is a 403048 (rate limit exceeded) error
+func IsRateLimited(err error) bool {
+	return accounts.IsRateLimited(err)
+}
+
+// IsInvalidParameter reports whether err is a 400006 (invalid parameter value) error
+func IsInvalidParameter(err error) bool {
+	return accounts.IsInvalidParameter(err)
+}
+
+// IsValidationError reports whether err is a 400009 (schema validation) error
+func IsValidationError(err error) bool {
+	return accounts.IsValidationError(err)
+}
+
+// IsPendingRegistration reports whether err is a 206001 (account pending registration) error
+func IsPendingRegistration(err error) bool {
+	return accounts.IsPendingRegistration(err)
+}
+
+// IsPendingVerification reports whether err is a 206002 (account pending verification) error
+func IsPendingVerification(err error) bool {
+	return accounts.IsPendingVerification(err)
+}
+
+// IsInvalidLogin reports whether err is a 403042 (invalid loginID or password) error
+func IsInvalidLogin(err error) bool {
+	return accounts.IsInvalidLogin(err)
+}
+
+// IsLoginIdentifierConflict reports whether err is a 403043 (login identifier exists) error
+func IsLoginIdentifierConflict(err error) bool {
+	return accounts.IsLoginIdentifierConflict(err)
+}
+
+// IsPasswordPolicyViolation reports whether err is a *PasswordPolicyError
+func IsPasswordPolicyViolation(err error) bool {
+	return accounts.IsPasswordPolicyViolation(err)
+}
@@ -7,36 +7,40 @@ type Gigya struct {
 	userKey     string
 	secretKey   string
 	apiDomain   string
+	options     []accounts.Option
 	AccountsAPI *accounts.AccountsAPI
 }
 
-func NewGigya(apiKey, userKey, secretKey, apiDomain string) *Gigya {
+// NewGigya creates a Gigya client. The options (HTTP client, request signing...)
+// are kept and applied again whenever the credentials change.
+func NewGigya(apiKey, userKey, secretKey, apiDomain string, opts ...accounts.Option) *Gigya {
 
 	return &Gigya{
 		apiKey:      apiKey,
 		userKey:     userKey,
 		secretKey:   secretKey,
 		apiDomain:   apiDomain,
-		AccountsAPI: accounts.NewAccountsAPI(apiKey, userKey, secretKey, apiDomain),
+		options:     opts,
+		AccountsAPI: accounts.NewAccountsAPI(apiKey, userKey, secretKey, apiDomain, opts...),
 	}
 }
 
 func (g *Gigya) SetApiKey(apiKey string) {
 	g.apiKey = apiKey
-	g.AccountsAPI = accounts.NewAccountsAPI(apiKey, g.userKey, g.secretKey, g.apiDomain)
+	g.AccountsAPI = accounts.NewAccountsAPI(apiKey, g.userKey, g.secretKey, g.apiDomain, g.options...)
 }
 
 func (g *Gigya) SetUserKey(userKey string) {
 	g.userKey = userKey
-	g.AccountsAPI = accounts.NewAccountsAPI(g.apiKey, userKey, g.secretKey, g.apiDomain)
+	g.AccountsAPI = accounts.NewAccountsAPI(g.apiKey, userKey, g.secretKey, g.apiDomain, g.options...)
 }
 
 func (g *Gigya) SetSecretKey(secretKey string) {
 	g.secretKey = secretKey
-	g.AccountsAPI = accounts.NewAccountsAPI(g.apiKey, g.userKey, secretKey, g.apiDomain)
+	g.AccountsAPI = accounts.NewAccountsAPI(g.apiKey, g.userKey, secretKey, g.apiDomain, g.options...)
 }
 
 func (g *Gigya) SetApiDomain(apiDomain string) {
 	g.apiDomain = apiDomain
-	g.AccountsAPI = accounts.NewAccountsAPI(g.apiKey, g.userKey, g.secretKey, apiDomain)
+	g.AccountsAPI = accounts.NewAccountsAPI(g.apiKey, g.userKey, g.secretKey, apiDomain, g.options...)
 }
@@ -0,0 +1,505 @@
+package gigyatest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gigya-module-go/accounts"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │            RECORD / REPLAY               │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// Mode selects whether a Recorder talks to the real API or replays a cassette
+type Mode int
+
+const (
+	// ModeReplay answers from the cassette file and never touches the network
+	ModeReplay Mode = iota
+	// ModeRecord sends the requests to the real API and appends them to the cassette
+	ModeRecord
+)
+
+// RecordEnv is the environment variable read by ModeFromEnv
+const RecordEnv = "GIGYATEST_RECORD"
+
+// ModeFromEnv returns ModeRecord when GIGYATEST_RECORD is set to a true value
+// ("1", "true"...) and ModeReplay otherwise, so CI always replays.
+func ModeFromEnv() Mode {
+	if record, _ := strconv.ParseBool(os.Getenv(RecordEnv)); record {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// Parameters never written to a cassette nor used to match requests:
+// credentials, and the values that change on every signed request
+var scrubbedParams = map[string]bool{
+	"apiKey":    true,
+	"userKey":   true,
+	"secret":    true,
+	"sig":       true,
+	"nonce":     true,
+	"timestamp": true,
+}
+
+// Keys whose values are always replaced by RedactedSecret in the recorded params
+// and bodies, whatever ScrubPII says: passwords, tokens and session credentials.
+// Add the secrets of any new method here.
+var secretKeys = map[string]bool{
+	"password":               true,
+	"newPassword":            true,
+	"hashedPassword":         true,
+	"compoundHashedPassword": true,
+	"securityAnswer":         true,
+	"passwordResetToken":     true,
+	"regToken":               true,
+	"captchaToken":           true,
+	"sessionToken":           true,
+	"sessionSecret":          true,
+	"cookieValue":            true,
+	"id_token":               true,
+	"UIDSignature":           true,
+}
+
+// RedactedSecret replaces the secrets in a cassette. Unlike the PII placeholders
+// it does not depend on the value, so a recorded password cannot be guessed back.
+const RedactedSecret = "redacted-secret"
+
+// DefaultPIIKeys are the JSON keys whose values are scrubbed when ScrubPII is set
+var DefaultPIIKeys = []string{
+	"email", "emails", "loginID", "username",
+	"firstName", "lastName", "nickname",
+	"phones", "address", "zip", "city",
+	"birthDay", "birthMonth", "birthYear",
+	"photoURL", "thumbnailURL",
+}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// queryLiteral matches a string or number literal of a search query
+const queryLiteral = `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|-?\d+(?:\.\d+)?`
+
+// queryCondition matches a condition of a search query: the field, the operator
+// and the literal (or the list of literals) it is compared with
+var queryCondition = regexp.MustCompile(`(?i)([A-Za-z_][A-Za-z0-9_.]*)(\s*(?:!=|<>|<=|>=|=|<|>|\s(?:not\s+)?(?:contains|in)\s)\s*)(\((?:\s*(?:` + queryLiteral + `)\s*,?)*\)|` + queryLiteral + `)`)
+
+var queryLiteralPattern = regexp.MustCompile(queryLiteral)
+
+var placeholderPattern = regexp.MustCompile(`^(?:user-[0-9a-f]{12}@example\.com|redacted-[0-9a-f]{12})$`)
+
+// Interaction is a recorded request/response pair
+type Interaction struct {
+	Method     string            `json:"method"`               // API method, e.g. "accounts.search"
+	Params     map[string]string `json:"params"`               // Normalized request parameters
+	Status     int               `json:"status"`               // HTTP status
+	RetryAfter string            `json:"retryAfter,omitempty"` // Retry-After header, if any
+	Body       json.RawMessage   `json:"body"`                 // Response body
+}
+
+// Cassette is the content of a fixture file
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// RecorderOptions configures a Recorder
+type RecorderOptions struct {
+	// ScrubPII replaces personal data in the recorded params and bodies by
+	// stable placeholders: the values of PIIKeys, the literals a search query
+	// compares with a PII field (profile.lastName = "Doe") and any e-mail address.
+	// The same value always gives the same placeholder, so replays still match.
+	// Passwords, tokens and session credentials are always redacted.
+	ScrubPII bool
+	// PIIKeys overrides DefaultPIIKeys
+	PIIKeys []string
+	// Transport sends the real requests in ModeRecord (http.DefaultTransport when nil)
+	Transport http.RoundTripper
+}
+
+// Recorder is an http.RoundTripper that records CDC traffic to a cassette file
+// or replays it. Plug it into the client with Option:
+//
+//	rec, err := gigyatest.NewRecorder("testdata/search.json", gigyatest.ModeFromEnv(), gigyatest.RecorderOptions{ScrubPII: true})
+//	api := accounts.NewAccountsAPI(apiKey, userKey, secret, domain, rec.Option())
+//
+// In replay, a request is answered by the first unused interaction with the same
+// method and normalized params, so repeated calls replay their responses in order.
+type Recorder struct {
+	path    string
+	mode    Mode
+	opts    RecorderOptions
+	piiKeys map[string]bool
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// NewRecorder creates a recorder for the cassette at path.
+// In ModeReplay the file must exist; in ModeRecord it is created or overwritten.
+func NewRecorder(path string, mode Mode, opts RecorderOptions) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode, opts: opts, piiKeys: map[string]bool{}}
+	keys := opts.PIIKeys
+	if keys == nil {
+		keys = DefaultPIIKeys
+	}
+	for _, key := range keys {
+		r.piiKeys[key] = true
+	}
+
+	if mode == ModeReplay {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("gigyatest: loading cassette: %w", err)
+		}
+		if err := json.Unmarshal(content, &r.cassette); err != nil {
+			return nil, fmt.Errorf("gigyatest: invalid cassette %s: %w", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+	return r, nil
+}
+
+// Client returns an *http.Client using the recorder as transport
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Option returns the accounts.Option plugging the recorder into an AccountsAPI
+func (r *Recorder) Option() accounts.Option {
+	return accounts.WithHTTPClient(r.Client())
+}
+
+// Unused returns the recorded interactions not replayed yet, to check a test
+// sent every expected request
+func (r *Recorder) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unused []Interaction
+	for i, used := range r.used {
+		if !used {
+			unused = append(unused, r.cassette.Interactions[i])
+		}
+	}
+	return unused
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := strings.TrimPrefix(req.URL.Path, "/")
+
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		if requestBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+	values, err := url.ParseQuery(string(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("gigyatest: invalid request body: %w", err)
+	}
+	for key, value := range req.URL.Query() {
+		values[key] = value
+	}
+	params := r.normalize(values)
+
+	if r.mode == ModeRecord {
+		return r.record(req, method, params)
+	}
+	return r.replay(req, method, params)
+}
+
+func (r *Recorder) record(req *http.Request, method string, params map[string]string) (*http.Response, error) {
+	transport := r.opts.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	recorded := r.scrubJSON(body)
+	if !json.Valid(recorded) {
+		// Keep non JSON bodies (HTML error pages...) as a JSON string
+		recorded, _ = json.Marshal(string(body))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Method:     method,
+		Params:     params,
+		Status:     resp.StatusCode,
+		RetryAfter: resp.Header.Get("Retry-After"),
+		Body:       recorded,
+	})
+	if err := r.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, method string, params map[string]string) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || interaction.Method != method || !equalParams(interaction.Params, params) {
+			continue
+		}
+		r.used[i] = true
+
+		body := []byte(interaction.Body)
+		var text string
+		if json.Unmarshal(body, &text) == nil {
+			body = []byte(text)
+		}
+		header := http.Header{"Content-Type": []string{"application/json; charset=utf-8"}}
+		if interaction.RetryAfter != "" {
+			header.Set("Retry-After", interaction.RetryAfter)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+			StatusCode:    interaction.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	encoded, _ := json.Marshal(params)
+	return nil, fmt.Errorf("gigyatest: no recorded interaction for %s %s in %s", method, encoded, r.path)
+}
+
+// save writes the cassette through a temporary file. Callers hold mu.
+func (r *Recorder) save() error {
+	content, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, append(content, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.path)
+}
+
+// normalize drops the credentials and volatile params, re-encodes the JSON
+// params with sorted keys, redacts the secrets and scrubs the PII when configured
+func (r *Recorder) normalize(values url.Values) map[string]string {
+	params := map[string]string{}
+	for key, value := range values {
+		if scrubbedParams[key] || len(value) == 0 {
+			continue
+		}
+		v := value[0]
+		if secretKeys[key] {
+			params[key] = redact(v)
+			continue
+		}
+		trimmed := strings.TrimSpace(v)
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			var decoded interface{}
+			if json.Unmarshal([]byte(trimmed), &decoded) == nil {
+				decoded = r.scrubValue(decoded, false)
+				encoded, _ := json.Marshal(decoded)
+				params[key] = string(encoded)
+				continue
+			}
+		}
+		if r.opts.ScrubPII {
+			switch {
+			case r.piiKeys[key]:
+				v = placeholder(v)
+			case key == "query":
+				v = r.scrubQuery(scrubEmails(v))
+			default:
+				v = scrubEmails(v)
+			}
+		}
+		params[key] = v
+	}
+	return params
+}
+
+// scrubJSON redacts the secrets and scrubs the PII of a JSON body, returning it
+// unchanged when it is not JSON
+func (r *Recorder) scrubJSON(body []byte) json.RawMessage {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return body
+	}
+	encoded, err := json.Marshal(r.scrubValue(decoded, false))
+	if err != nil {
+		return body
+	}
+	return encoded
+}
+
+// scrubValue walks a decoded JSON value; every string below a secret key is
+// redacted, and with ScrubPII every string below a PII key is replaced
+func (r *Recorder) scrubValue(v interface{}, pii bool) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if secretKeys[key] {
+				value[key] = redactValue(child)
+				continue
+			}
+			value[key] = r.scrubValue(child, pii || (r.opts.ScrubPII && r.piiKeys[key]))
+		}
+		return value
+	case []interface{}:
+		for i, child := range value {
+			value[i] = r.scrubValue(child, pii)
+		}
+		return value
+	case string:
+		if pii {
+			return placeholder(value)
+		}
+		if r.opts.ScrubPII {
+			return scrubEmails(value)
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+// scrubQuery replaces the literals compared with a PII field of a search query,
+// e.g. profile.firstName = "Jane", by quoted placeholders
+func (r *Recorder) scrubQuery(query string) string {
+	return queryCondition.ReplaceAllStringFunc(query, func(condition string) string {
+		match := queryCondition.FindStringSubmatch(condition)
+		if !r.piiField(match[1]) {
+			return condition
+		}
+		literals := queryLiteralPattern.ReplaceAllStringFunc(match[3], func(literal string) string {
+			if value, ok := unquote(literal); ok {
+				return literal[:1] + placeholder(value) + literal[:1]
+			}
+			return `"` + placeholder(literal) + `"`
+		})
+		return match[1] + match[2] + literals
+	})
+}
+
+// piiField reports whether a segment of a query field path is a PII key,
+// e.g. profile.email or emails.verified
+func (r *Recorder) piiField(field string) bool {
+	for _, segment := range strings.Split(field, ".") {
+		if r.piiKeys[segment] {
+			return true
+		}
+	}
+	return false
+}
+
+// unquote returns the value of a quoted query literal
+func unquote(literal string) (string, bool) {
+	if literal[0] != '"' && literal[0] != '\'' {
+		return "", false
+	}
+	var value strings.Builder
+	escaped := false
+	for _, c := range literal[1 : len(literal)-1] {
+		if c == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		value.WriteRune(c)
+	}
+	return value.String(), true
+}
+
+// redactValue replaces every string of a decoded JSON value by RedactedSecret
+func redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			value[key] = redactValue(child)
+		}
+		return value
+	case []interface{}:
+		for i, child := range value {
+			value[i] = redactValue(child)
+		}
+		return value
+	case string:
+		return redact(value)
+	default:
+		return v
+	}
+}
+
+// redact replaces a non empty secret by RedactedSecret
+func redact(s string) string {
+	if s == "" {
+		return s
+	}
+	return RedactedSecret
+}
+
+// placeholder replaces s by a stable value; e-mail addresses stay e-mail addresses
+func placeholder(s string) string {
+	if s == "" || placeholderPattern.MatchString(s) {
+		return s
+	}
+	sum := sha256.Sum256([]byte(strings.ToLower(s)))
+	hash := hex.EncodeToString(sum[:])[:12]
+	if emailPattern.MatchString(s) {
+		return "user-" + hash + "@example.com"
+	}
+	return "redacted-" + hash
+}
+
+func scrubEmails(s string) string {
+	return emailPattern.ReplaceAllStringFunc(s, placeholder)
+}
+
+func equalParams(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if v, ok := b[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+package gigyatest_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/gigyatest"
+)
+
+// recordedParams returns the params of the recorded interactions of a cassette
+func recordedParams(t *testing.T, path string) []map[string]string {
+	t.Helper()
+	rec, err := gigyatest.NewRecorder(path, gigyatest.ModeReplay, gigyatest.RecorderOptions{})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	var params []map[string]string
+	for _, interaction := range rec.Unused() {
+		params = append(params, interaction.Params)
+	}
+	return params
+}
+
+func TestRecordReplay(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	const (
+		email    = "jane.doe@example.com"
+		password = "S3cret-Passw0rd!"
+		query    = `select UID, profile from accounts where profile.lastName = "Doe" and profile.firstName in ('Jane', "Joan")`
+	)
+
+	// run sends the same requests when recording and replaying
+	run := func(api *accounts.AccountsAPI) (accounts.LoginResult, accounts.Accounts) {
+		t.Helper()
+		ctx := context.Background()
+		registered, err := api.RegisterContext(ctx, accounts.RegisterParams{
+			Email:                email,
+			Password:             password,
+			Profile:              &accounts.Profile{FirstName: "Jane", LastName: "Doe"},
+			FinalizeRegistration: true,
+		})
+		if err != nil || registered.Status != accounts.RegistrationCompleted {
+			t.Fatalf("Register = %+v, %v", registered, err)
+		}
+		login, err := api.LoginContext(ctx, accounts.LoginParams{LoginID: email, Password: password})
+		if err != nil {
+			t.Fatalf("Login: %v", err)
+		}
+		results, total, err := api.Search(query, 10)
+		if err != nil || total != 1 {
+			t.Fatalf("Search = %d results, %v", total, err)
+		}
+		return login, results
+	}
+
+	rec, err := gigyatest.NewRecorder(path, gigyatest.ModeRecord, gigyatest.RecorderOptions{ScrubPII: true, Transport: srv.Client().Transport})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	login, _ := run(srv.AccountsAPI(rec.Option()))
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading the cassette: %v", err)
+	}
+	for name, value := range map[string]string{
+		"secret key":     srv.SecretKey,
+		"user key":       srv.UserKey,
+		"API key":        srv.APIKey,
+		"password":       password,
+		"session token":  login.SessionInfo.SessionToken,
+		"session secret": login.SessionInfo.SessionSecret,
+		"e-mail":         email,
+		"first name":     "Jane",
+		"last name":      "Doe",
+	} {
+		if value != "" && strings.Contains(string(content), value) {
+			t.Errorf("cassette holds the %s %q", name, value)
+		}
+	}
+	if !strings.Contains(string(content), gigyatest.RedactedSecret) {
+		t.Error("cassette holds no redacted secret")
+	}
+
+	// The replay matches the scrubbed requests and answers with the scrubbed bodies
+	replay, err := gigyatest.NewRecorder(path, gigyatest.ModeReplay, gigyatest.RecorderOptions{ScrubPII: true})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	calls := len(srv.Calls("accounts.search"))
+	_, results := run(accounts.NewAccountsAPI(srv.APIKey, srv.UserKey, srv.SecretKey, srv.Domain(), replay.Option()))
+	if len(results) != 1 || results[0].Profile.FirstName == "Jane" || results[0].Profile.FirstName == "" {
+		t.Errorf("replayed results = %+v, want the scrubbed account", results)
+	}
+	if unused := replay.Unused(); len(unused) != 0 {
+		t.Errorf("%d interactions not replayed", len(unused))
+	}
+	if got := len(srv.Calls("accounts.search")); got != calls {
+		t.Errorf("replay sent %d searches to the server", got-calls)
+	}
+}
+
+func TestRecordQueryScrubbing(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		absent []string
+		kept   []string
+	}{
+		{
+			name:   "quoted names",
+			query:  `select * from accounts where profile.firstName = "Jane" or profile.lastName = 'O\'Brien'`,
+			absent: []string{"Jane", "Brien"},
+			kept:   []string{`profile.firstName = "redacted-`, `profile.lastName = 'redacted-`},
+		},
+		{
+			name:   "lists and contains",
+			query:  `select * from accounts where profile.city in ("Madrid", 'Paris') and profile.nickname contains "jj"`,
+			absent: []string{"Madrid", "Paris", `"jj"`},
+			kept:   []string{"profile.city in (", "profile.nickname contains"},
+		},
+		{
+			name:   "numbers",
+			query:  `select * from accounts where profile.birthYear >= 1984 and data.points > 10`,
+			absent: []string{"1984"},
+			kept:   []string{"data.points > 10"},
+		},
+		{
+			name:   "e-mails",
+			query:  `select * from accounts where emails.verified contains "jane@acme.org" or data.contact = 'joan@acme.org'`,
+			absent: []string{"jane@acme.org", "joan@acme.org"},
+			kept:   []string{"@example.com"},
+		},
+		{
+			name:  "other fields",
+			query: `select * from accounts where data.tier = "gold" and UID = 'uid-1'`,
+			kept:  []string{`data.tier = "gold" and UID = 'uid-1'`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := gigyatest.NewServer()
+			defer srv.Close()
+			path := filepath.Join(t.TempDir(), "cassette.json")
+
+			rec, err := gigyatest.NewRecorder(path, gigyatest.ModeRecord, gigyatest.RecorderOptions{ScrubPII: true, Transport: srv.Client().Transport})
+			if err != nil {
+				t.Fatalf("NewRecorder: %v", err)
+			}
+			if _, _, err := srv.AccountsAPI(rec.Option()).Search(tt.query, 10); err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+
+			params := recordedParams(t, path)
+			if len(params) != 1 {
+				t.Fatalf("recorded %d interactions, want 1", len(params))
+			}
+			recorded := params[0]["query"]
+			for _, value := range tt.absent {
+				if strings.Contains(recorded, value) {
+					t.Errorf("recorded query %s holds %q", recorded, value)
+				}
+			}
+			for _, value := range tt.kept {
+				if !strings.Contains(recorded, value) {
+					t.Errorf("recorded query %s lost %q", recorded, value)
+				}
+			}
+
+			// The same query is replayed from the scrubbed cassette
+			replay, err := gigyatest.NewRecorder(path, gigyatest.ModeReplay, gigyatest.RecorderOptions{ScrubPII: true})
+			if err != nil {
+				t.Fatalf("NewRecorder: %v", err)
+			}
+			if _, _, err := accounts.NewAccountsAPI(srv.APIKey, srv.UserKey, srv.SecretKey, srv.Domain(), replay.Option()).Search(tt.query, 10); err != nil {
+				t.Errorf("replayed Search: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package gigyatest
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"gigya-module-go/accounts"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │                   JWT                    │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// defaultJWTExpiration is the validity of getJWT tokens without expiration, as in CDC
+const defaultJWTExpiration = 300
+
+// getJWT issues an id_token for targetUID signed with the current server key.
+// The requested fields are added as dotted claims ("profile.firstName").
+func (s *Server) getJWT(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	UID := params.Get("targetUID")
+	if UID == "" {
+		return nil, newError(ErrorCodeMissingParameter, "targetUID")
+	}
+	account, ok := s.accounts[UID]
+	if !ok {
+		return nil, newError(accounts.ErrorCodeNotFound, "account "+UID+" not found")
+	}
+
+	expiration := defaultJWTExpiration
+	if value := params.Get("expiration"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			return nil, newError(ErrorCodeInvalidParameter, "expiration")
+		}
+		expiration = seconds
+	}
+
+	claims := s.idTokenClaims(UID, expiration)
+	for _, field := range splitList(params.Get("fields")) {
+		if value, ok := lookup(account, field); ok {
+			claims[field] = value
+		}
+	}
+
+	token, err := s.signJWT(claims)
+	if err != nil {
+		return nil, newError(accounts.ErrorCodeGeneralServerError, err.Error())
+	}
+	return map[string]interface{}{"id_token": token}, nil
+}
+
+// signJWT signs claims with RS256 and the current server key. Callers hold mu.
+func (s *Server) signJWT(claims map[string]interface{}) (string, error) {
+	return signToken(s.key, "RS256", s.kid, claims)
+}
+
+// idTokenClaims returns the claims of an id_token issued now for UID
+func (s *Server) idTokenClaims(UID string, expiration int) map[string]interface{} {
+	now := time.Now().Unix()
+	return map[string]interface{}{
+		"iss":    "https://fidm.gigya.com/jwt/" + s.APIKey + "/",
+		"apiKey": s.APIKey,
+		"iat":    now,
+		"exp":    now + int64(expiration),
+		"sub":    UID,
+	}
+}
+
+// IDTokenClaims returns the claims of a token issued now for UID, as getJWT
+// issues them. Change them to build invalid tokens with IDToken.
+func (s *Server) IDTokenClaims(UID string) map[string]interface{} {
+	return s.idTokenClaims(UID, defaultJWTExpiration)
+}
+
+// IDToken signs claims with RS256 and the current server key, as getJWT does
+func (s *Server) IDToken(claims map[string]interface{}) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SignToken(s.key, "RS256", s.kid, claims)
+}
+
+// SignToken builds a token with the given header alg and kid, to test verifiers:
+// RS256, RS384 and RS512 are signed with key, HS256 with an HMAC keyed by the
+// modulus of key (the algorithm confusion attack) and "none" is not signed.
+func SignToken(key *rsa.PrivateKey, alg, kid string, claims map[string]interface{}) string {
+	token, err := signToken(key, alg, kid, claims)
+	if err != nil {
+		panic(fmt.Sprintf("gigyatest: signing the token: %v", err))
+	}
+	return token
+}
+
+func signToken(key *rsa.PrivateKey, alg, kid string, claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	switch alg {
+	case "none":
+	case "HS256":
+		mac := hmac.New(sha256.New, key.PublicKey.N.Bytes())
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case "RS256", "RS384", "RS512":
+		hash := map[string]crypto.Hash{"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512}[alg]
+		hasher := hash.New()
+		hasher.Write([]byte(signed))
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, hash, hasher.Sum(nil)); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unsupported alg %q", alg)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,194 @@
+package gigyatest
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gigya-module-go/accounts"
+
+	"github.com/google/uuid"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │            LOGIN AND SESSIONS            │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// Sessions returns the number of open sessions of the user
+func (s *Server) Sessions(UID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, owner := range s.sessions {
+		if owner == UID {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Server) login(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	loginID := params.Get("loginID")
+	if loginID == "" {
+		return nil, newError(ErrorCodeMissingParameter, "loginID")
+	}
+	account := s.accountByLoginID(loginID)
+	if account == nil {
+		account = s.accountByUsername(loginID)
+	}
+	if account == nil || !checkPassword(account, params.Get("password")) {
+		return nil, newError(accounts.ErrorCodeInvalidLoginID, "")
+	}
+
+	if account["isRegistered"] != true || (s.RequireEmailVerification && account["isVerified"] != true) {
+		code := accounts.ErrorCodeAccountPendingRegistration
+		if account["isRegistered"] == true {
+			code = accounts.ErrorCodeAccountPendingVerification
+		}
+		regToken := strings.ReplaceAll(uuid.New().String(), "-", "")
+		s.regTokens[regToken] = account["UID"].(string)
+		return pending(code, "", account["UID"], regToken)
+	}
+
+	now := time.Now()
+	account["lastLogin"] = now.UTC().Format("2006-01-02T15:04:05.000Z")
+	account["lastLoginTimestamp"] = float64(now.UnixMilli())
+
+	response := includeSections(account, params.Get("include"))
+	response["UID"] = account["UID"]
+	response["sessionInfo"] = s.newSession(account["UID"].(string), params)
+	return response, nil
+}
+
+func (s *Server) notifyLogin(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	UID := params.Get("siteUID")
+	if UID == "" {
+		return nil, newError(ErrorCodeMissingParameter, "siteUID")
+	}
+	if _, ok := s.accounts[UID]; !ok {
+		// A new user of the site gets a registered account with the site UID
+		account := map[string]interface{}{"UID": UID, "isRegistered": true, "isActive": true}
+		if regSource := params.Get("regSource"); regSource != "" {
+			account["regSource"] = regSource
+		}
+		s.store(account, time.Now())
+	}
+
+	return map[string]interface{}{
+		"UID":         UID,
+		"sessionInfo": s.newSession(UID, params),
+	}, nil
+}
+
+func (s *Server) logout(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	account, apiErr := s.lookupAccount(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	for session, owner := range s.sessions {
+		if owner == account["UID"] {
+			delete(s.sessions, session)
+		}
+	}
+	return map[string]interface{}{"UID": account["UID"]}, nil
+}
+
+func (s *Server) verifyLogin(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	account, apiErr := s.lookupAccount(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	for _, owner := range s.sessions {
+		if owner == account["UID"] {
+			response := includeSections(account, params.Get("include"))
+			response["UID"] = account["UID"]
+			return response, nil
+		}
+	}
+	return nil, newError(accounts.ErrorCodeNotFound, "the user is not logged in")
+}
+
+func (s *Server) getConflictingAccount(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	regToken := params.Get("regToken")
+	if regToken == "" {
+		return nil, newError(ErrorCodeMissingParameter, "regToken")
+	}
+	account := s.accounts[s.conflicts[regToken]]
+	if account == nil {
+		return nil, newError(ErrorCodeInvalidParameter, "regToken is invalid or expired")
+	}
+
+	loginID := ""
+	if emails := loginEmails(account); len(emails) > 0 {
+		loginID = emails[0]
+	}
+	return map[string]interface{}{
+		"conflictingAccount": map[string]interface{}{
+			"loginID":        loginID,
+			"loginProviders": []string{"site"},
+		},
+	}, nil
+}
+
+// newSession opens a session: a token and secret, or a cookie for targetEnv browser
+func (s *Server) newSession(UID string, params url.Values) map[string]interface{} {
+	secret := make([]byte, 20)
+	rand.Read(secret)
+	value := strings.ReplaceAll(uuid.New().String(), "-", "")
+
+	expiresIn := "0"
+	if expiration, err := strconv.Atoi(params.Get("sessionExpiration")); err == nil && expiration > 0 {
+		expiresIn = strconv.Itoa(expiration)
+	}
+
+	if params.Get("targetEnv") == accounts.TargetEnvBrowser {
+		cookie := "LT3_" + value
+		s.sessions[cookie] = UID
+		return map[string]interface{}{
+			"cookieName":  "glt_" + s.APIKey,
+			"cookieValue": cookie,
+		}
+	}
+	token := "st2." + value
+	s.sessions[token] = UID
+	return map[string]interface{}{
+		"sessionToken":  token,
+		"sessionSecret": base64.StdEncoding.EncodeToString(secret),
+		"expires_in":    expiresIn,
+	}
+}
+
+// includeSections copies the account sections listed in include
+// (profile and data when empty)
+func includeSections(account map[string]interface{}, include string) map[string]interface{} {
+	sections := splitList(include)
+	if len(sections) == 0 {
+		sections = []string{"profile", "data"}
+	}
+	response := map[string]interface{}{}
+	for _, section := range sections {
+		if value, ok := account[section]; ok {
+			response[section] = value
+		}
+	}
+	for _, flag := range []string{"isRegistered", "isVerified", "isActive"} {
+		if value, ok := account[flag]; ok {
+			response[flag] = value
+		}
+	}
+	return deepCopy(response)
+}
+
+// accountByUsername returns the account with the username. Callers hold mu.
+func (s *Server) accountByUsername(username string) map[string]interface{} {
+	for _, account := range s.accounts {
+		if name, _ := account["username"].(string); name != "" && strings.EqualFold(name, username) {
+			return account
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package gigyatest
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gigya-module-go/accounts"
+
+	"github.com/google/uuid"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │           PASSWORD MANAGEMENT            │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// PasswordResetToken returns the last reset token issued for loginID, as the
+// reset email would carry it
+func (s *Server) PasswordResetToken(loginID string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account := s.accountByLoginID(loginID)
+	if account == nil {
+		return "", false
+	}
+	for token, UID := range s.resetTokens {
+		if UID == account["UID"] {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+func (s *Server) resetPassword(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	if token := params.Get("passwordResetToken"); token != "" {
+		account := s.accounts[s.resetTokens[token]]
+		if account == nil {
+			return nil, newError(ErrorCodeInvalidParameter, "passwordResetToken is invalid or expired")
+		}
+		newPassword := params.Get("newPassword")
+		if newPassword == "" {
+			return nil, newError(ErrorCodeMissingParameter, "newPassword")
+		}
+		if apiErr := s.checkPasswordPolicy(account, newPassword); apiErr != nil {
+			return nil, apiErr
+		}
+		s.setPassword(account, newPassword)
+		delete(s.resetTokens, token)
+		return map[string]interface{}{"UID": account["UID"]}, nil
+	}
+
+	loginID := params.Get("loginID")
+	if loginID == "" {
+		return nil, newError(ErrorCodeMissingParameter, "loginID")
+	}
+	account := s.accountByLoginID(loginID)
+	if account == nil {
+		account = s.accountByUsername(loginID)
+	}
+	if account == nil {
+		return nil, newError(ErrorCodeLoginIDNotFound, "")
+	}
+
+	// A new reset invalidates the previous token
+	for token, UID := range s.resetTokens {
+		if UID == account["UID"] {
+			delete(s.resetTokens, token)
+		}
+	}
+	token := strings.ReplaceAll(uuid.New().String(), "-", "")
+	s.resetTokens[token] = account["UID"].(string)
+
+	if sendEmail, err := strconv.ParseBool(params.Get("sendEmail")); err == nil && !sendEmail {
+		return map[string]interface{}{"passwordResetToken": token}, nil
+	}
+	return map[string]interface{}{}, nil
+}
+
+// checkPasswordPolicy validates a new password against PasswordMinLength and,
+// for an existing account, PasswordHistory
+func (s *Server) checkPasswordPolicy(account map[string]interface{}, password string) *accounts.APIError {
+	if len(password) < s.PasswordMinLength {
+		apiErr := newError(accounts.ErrorCodeValidation, "")
+		apiErr.ValidationErrors = []accounts.ValidationError{{
+			ErrorCode: ErrorCodeInvalidParameter,
+			Message:   "Password does not meet complexity requirements",
+			FieldName: "password",
+		}}
+		return apiErr
+	}
+	if account == nil || s.PasswordHistory <= 0 {
+		return nil
+	}
+
+	recent := []map[string]interface{}{}
+	if current, ok := account["password"].(map[string]interface{}); ok {
+		recent = append(recent, current)
+	}
+	old := s.oldPasswords[account["UID"].(string)]
+	recent = append(recent, old[max(0, len(old)-s.PasswordHistory+1):]...)
+	for _, previous := range recent {
+		if checkPassword(map[string]interface{}{"password": previous}, password) {
+			return newError(accounts.ErrorCodeOldPasswordUsed, "")
+		}
+	}
+	return nil
+}
+
+// setPassword replaces the password hash, keeping the old one for the history
+func (s *Server) setPassword(account map[string]interface{}, password string) {
+	UID := account["UID"].(string)
+	if current, ok := account["password"].(map[string]interface{}); ok {
+		s.oldPasswords[UID] = append(s.oldPasswords[UID], current)
+	}
+	account["password"] = hashPassword(password)
+}
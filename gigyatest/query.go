@@ -0,0 +1,720 @@
+package gigyatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │          QUERY LANGUAGE SUBSET           │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// The fake understands this subset of the accounts.search query language:
+//
+//	select (* | field, count(*), sum(field), min(field), max(field), avg(field)...)
+//	from accounts
+//	[where <condition>]
+//	[group by field, ...]
+//	[order by field [asc|desc], ...]
+//	[start n] [limit n]
+//
+// Conditions: = != <> < > <= >=, contains, not contains, in (...), is null,
+// is not null, and, or, not, parentheses. Values: 'single' or "double" quoted
+// strings with backslash escapes, numbers, true, false, null.
+
+type query struct {
+	fields  []selectField
+	where   condition
+	groupBy []string
+	orderBy []orderField
+	start   int
+	limit   int
+}
+
+type selectField struct {
+	name      string // Column name as returned, e.g. "profile.email" or "count(*)"
+	aggregate string // count, sum, min, max, avg or "" for plain fields
+	field     string // Field of the aggregate, "*" for count(*)
+}
+
+type orderField struct {
+	field string
+	desc  bool
+}
+
+type condition func(account map[string]interface{}) bool
+
+/* ─────────────────────────── tokenizer ─────────────────────────── */
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokString
+	tokNumber
+	tokSymbol
+	tokEOF
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'' || r == '"':
+			quote := r
+			var b strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != quote; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					switch runes[i] {
+					case 'n':
+						b.WriteRune('\n')
+					case 'r':
+						b.WriteRune('\r')
+					case 't':
+						b.WriteRune('\t')
+					default:
+						b.WriteRune(runes[i])
+					}
+					continue
+				}
+				b.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			i++
+			tokens = append(tokens, token{kind: tokString, text: b.String(), value: b.String()})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E') {
+				i++
+			}
+			text := string(runes[start:i])
+			n, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", text)
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: text, value: n})
+		case unicode.IsLetter(r) || r == '_' || r == '$':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || strings.ContainsRune("_$.-", runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: string(runes[start:i])})
+		case strings.ContainsRune("(),*", r):
+			tokens = append(tokens, token{kind: tokSymbol, text: string(r)})
+			i++
+		case strings.ContainsRune("=!<>", r):
+			start := i
+			i++
+			if i < len(runes) && strings.ContainsRune("=>", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokSymbol, text: string(runes[start:i])})
+		default:
+			return nil, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+	return append(tokens, token{kind: tokEOF}), nil
+}
+
+/* ───────────────────────────── parser ──────────────────────────── */
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parseQuery(input string) (*query, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	q := &query{limit: -1}
+
+	if err := p.keyword("select"); err != nil {
+		return nil, err
+	}
+	if q.fields, err = p.selectFields(); err != nil {
+		return nil, err
+	}
+	if err := p.keyword("from"); err != nil {
+		return nil, err
+	}
+	if collection := p.next(); collection.kind != tokIdent || !strings.EqualFold(collection.text, "accounts") {
+		return nil, fmt.Errorf("unsupported collection %q", collection.text)
+	}
+
+	for p.peek().kind != tokEOF {
+		switch {
+		case p.isKeyword("where"):
+			p.next()
+			if q.where, err = p.orCondition(); err != nil {
+				return nil, err
+			}
+		case p.isKeyword("group"):
+			p.next()
+			if err := p.keyword("by"); err != nil {
+				return nil, err
+			}
+			for {
+				field := p.next()
+				if field.kind != tokIdent {
+					return nil, fmt.Errorf("expected field after group by")
+				}
+				q.groupBy = append(q.groupBy, field.text)
+				if !p.isSymbol(",") {
+					break
+				}
+				p.next()
+			}
+		case p.isKeyword("order"):
+			p.next()
+			if err := p.keyword("by"); err != nil {
+				return nil, err
+			}
+			for {
+				field := p.next()
+				if field.kind != tokIdent {
+					return nil, fmt.Errorf("expected field after order by")
+				}
+				order := orderField{field: field.text}
+				if p.isKeyword("desc") {
+					p.next()
+					order.desc = true
+				} else if p.isKeyword("asc") {
+					p.next()
+				}
+				q.orderBy = append(q.orderBy, order)
+				if !p.isSymbol(",") {
+					break
+				}
+				p.next()
+			}
+		case p.isKeyword("start"):
+			p.next()
+			if q.start, err = p.integer(); err != nil {
+				return nil, err
+			}
+		case p.isKeyword("limit"):
+			p.next()
+			if q.limit, err = p.integer(); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q", p.peek().text)
+		}
+	}
+	return q, nil
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && strings.EqualFold(t.text, keyword)
+}
+
+func (p *parser) isSymbol(symbol string) bool {
+	t := p.peek()
+	return t.kind == tokSymbol && t.text == symbol
+}
+
+func (p *parser) keyword(keyword string) error {
+	if !p.isKeyword(keyword) {
+		return fmt.Errorf("expected %s, found %q", keyword, p.peek().text)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) symbol(symbol string) error {
+	if !p.isSymbol(symbol) {
+		return fmt.Errorf("expected %q, found %q", symbol, p.peek().text)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) integer() (int, error) {
+	t := p.next()
+	if t.kind != tokNumber {
+		return 0, fmt.Errorf("expected number, found %q", t.text)
+	}
+	return int(t.value.(float64)), nil
+}
+
+func (p *parser) selectFields() ([]selectField, error) {
+	if p.isSymbol("*") {
+		p.next()
+		return nil, nil
+	}
+	var fields []selectField
+	for {
+		t := p.next()
+		if t.kind != tokIdent {
+			return nil, fmt.Errorf("expected field, found %q", t.text)
+		}
+		field := selectField{name: t.text}
+		if p.isSymbol("(") {
+			p.next()
+			arg := p.next()
+			if arg.kind != tokIdent && !(arg.kind == tokSymbol && arg.text == "*") {
+				return nil, fmt.Errorf("invalid argument of %s", t.text)
+			}
+			if err := p.symbol(")"); err != nil {
+				return nil, err
+			}
+			field.aggregate = strings.ToLower(t.text)
+			field.field = arg.text
+			field.name = field.aggregate + "(" + arg.text + ")"
+		}
+		fields = append(fields, field)
+		if !p.isSymbol(",") {
+			return fields, nil
+		}
+		p.next()
+	}
+}
+
+func (p *parser) orCondition() (condition, error) {
+	left, err := p.andCondition()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.andCondition()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(a map[string]interface{}) bool { return l(a) || right(a) }
+	}
+	return left, nil
+}
+
+func (p *parser) andCondition() (condition, error) {
+	left, err := p.notCondition()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.notCondition()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(a map[string]interface{}) bool { return l(a) && right(a) }
+	}
+	return left, nil
+}
+
+func (p *parser) notCondition() (condition, error) {
+	if p.isKeyword("not") {
+		p.next()
+		inner, err := p.notCondition()
+		if err != nil {
+			return nil, err
+		}
+		return func(a map[string]interface{}) bool { return !inner(a) }, nil
+	}
+	return p.primaryCondition()
+}
+
+func (p *parser) primaryCondition() (condition, error) {
+	if p.isSymbol("(") {
+		p.next()
+		inner, err := p.orCondition()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.symbol(")")
+	}
+
+	fieldToken := p.next()
+	if fieldToken.kind != tokIdent {
+		return nil, fmt.Errorf("expected field, found %q", fieldToken.text)
+	}
+	field := fieldToken.text
+
+	switch {
+	case p.isKeyword("is"):
+		p.next()
+		not := false
+		if p.isKeyword("not") {
+			p.next()
+			not = true
+		}
+		if err := p.keyword("null"); err != nil {
+			return nil, err
+		}
+		return func(a map[string]interface{}) bool {
+			v, ok := lookup(a, field)
+			isNull := !ok || v == nil
+			return isNull != not
+		}, nil
+
+	case p.isKeyword("contains"), p.isKeyword("not"):
+		not := p.isKeyword("not")
+		p.next()
+		if not {
+			if err := p.keyword("contains"); err != nil {
+				return nil, err
+			}
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		needle := strings.ToLower(fmt.Sprint(value))
+		return func(a map[string]interface{}) bool {
+			v, _ := lookup(a, field)
+			found := anyValue(v, func(x interface{}) bool {
+				s, ok := x.(string)
+				return ok && strings.Contains(strings.ToLower(s), needle)
+			})
+			return found != not
+		}, nil
+
+	case p.isKeyword("in"):
+		p.next()
+		if err := p.symbol("("); err != nil {
+			return nil, err
+		}
+		var values []interface{}
+		for {
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			if !p.isSymbol(",") {
+				break
+			}
+			p.next()
+		}
+		if err := p.symbol(")"); err != nil {
+			return nil, err
+		}
+		return func(a map[string]interface{}) bool {
+			v, _ := lookup(a, field)
+			return anyValue(v, func(x interface{}) bool {
+				for _, value := range values {
+					if compare(x, value) == 0 {
+						return true
+					}
+				}
+				return false
+			})
+		}, nil
+	}
+
+	op := p.next()
+	if op.kind != tokSymbol || !strings.Contains("= != <> < > <= >=", op.text) {
+		return nil, fmt.Errorf("expected operator after %s, found %q", field, op.text)
+	}
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	return func(a map[string]interface{}) bool {
+		v, ok := lookup(a, field)
+		if value == nil {
+			isNull := !ok || v == nil
+			return isNull == (op.text == "=")
+		}
+		if !ok || v == nil {
+			return op.text == "!=" || op.text == "<>"
+		}
+		return anyValue(v, func(x interface{}) bool {
+			c := compare(x, value)
+			switch op.text {
+			case "=":
+				return c == 0
+			case "!=", "<>":
+				return c != 0
+			case "<":
+				return c < 0
+			case ">":
+				return c > 0
+			case "<=":
+				return c <= 0
+			default:
+				return c >= 0
+			}
+		})
+	}, nil
+}
+
+func (p *parser) value() (interface{}, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokString, t.kind == tokNumber:
+		return t.value, nil
+	case t.kind == tokIdent && strings.EqualFold(t.text, "true"):
+		return true, nil
+	case t.kind == tokIdent && strings.EqualFold(t.text, "false"):
+		return false, nil
+	case t.kind == tokIdent && strings.EqualFold(t.text, "null"):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("expected value, found %q", t.text)
+	}
+}
+
+/* ──────────────────────────── evaluation ───────────────────────── */
+
+// lookup walks a dotted path
+func lookup(account map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = account
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// setPath sets a dotted path, creating the intermediate objects
+func setPath(object map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := object[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			object[key] = child
+		}
+		object = child
+	}
+	object[keys[len(keys)-1]] = value
+}
+
+// anyValue applies match to v, or to each element when v is an array
+func anyValue(v interface{}, match func(interface{}) bool) bool {
+	if list, ok := v.([]interface{}); ok {
+		for _, x := range list {
+			if match(x) {
+				return true
+			}
+		}
+		return false
+	}
+	return match(v)
+}
+
+// compare orders numbers numerically and everything else as text
+func compare(a, b interface{}) int {
+	fa, aNum := toNumber(a)
+	fb, bNum := toNumber(b)
+	if aNum && bNum {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil && !math.IsNaN(f)
+	default:
+		return 0, false
+	}
+}
+
+// isAggregate reports whether the query returns aggregate rows instead of accounts
+func (q *query) isAggregate() bool {
+	if len(q.groupBy) > 0 {
+		return true
+	}
+	for _, field := range q.fields {
+		if field.aggregate != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the matching accounts, sorted
+func (q *query) filter(all []map[string]interface{}) []map[string]interface{} {
+	var matches []map[string]interface{}
+	for _, account := range all {
+		if q.where == nil || q.where(account) {
+			matches = append(matches, account)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		for _, order := range q.orderBy {
+			a, _ := lookup(matches[i], order.field)
+			b, _ := lookup(matches[j], order.field)
+			if c := compareNullable(a, b); c != 0 {
+				return (c < 0) != order.desc
+			}
+		}
+		return false
+	})
+	return matches
+}
+
+func compareNullable(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	default:
+		return compare(a, b)
+	}
+}
+
+// project keeps the selected fields of an account
+func (q *query) project(account map[string]interface{}) map[string]interface{} {
+	if len(q.fields) == 0 {
+		return account
+	}
+	result := map[string]interface{}{}
+	for _, field := range q.fields {
+		if v, ok := lookup(account, field.name); ok {
+			setPath(result, field.name, v)
+		}
+	}
+	return result
+}
+
+// aggregate groups the accounts and computes the aggregate columns
+func (q *query) aggregate(accounts []map[string]interface{}) []map[string]interface{} {
+	type group struct {
+		row      map[string]interface{}
+		accounts []map[string]interface{}
+	}
+	var groups []*group
+	index := map[string]*group{}
+	for _, account := range accounts {
+		row := map[string]interface{}{}
+		var key []string
+		for _, field := range q.groupBy {
+			v, _ := lookup(account, field)
+			row[field] = v
+			key = append(key, fmt.Sprint(v))
+		}
+		k := strings.Join(key, "\x00")
+		g, ok := index[k]
+		if !ok {
+			g = &group{row: row}
+			index[k] = g
+			groups = append(groups, g)
+		}
+		g.accounts = append(g.accounts, account)
+	}
+	if len(groups) == 0 && len(q.groupBy) == 0 {
+		groups = append(groups, &group{row: map[string]interface{}{}})
+	}
+
+	rows := make([]map[string]interface{}, 0, len(groups))
+	for _, g := range groups {
+		for _, field := range q.fields {
+			if field.aggregate == "" {
+				continue
+			}
+			g.row[field.name] = aggregateValue(field, g.accounts)
+		}
+		rows = append(rows, g.row)
+	}
+	return rows
+}
+
+func aggregateValue(field selectField, accounts []map[string]interface{}) interface{} {
+	var values []float64
+	count := 0
+	for _, account := range accounts {
+		if field.field == "*" {
+			count++
+			continue
+		}
+		v, ok := lookup(account, field.field)
+		if !ok || v == nil {
+			continue
+		}
+		count++
+		if n, isNumber := toNumber(v); isNumber {
+			values = append(values, n)
+		}
+	}
+
+	switch field.aggregate {
+	case "count":
+		return count
+	case "sum", "avg":
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		if field.aggregate == "avg" {
+			if len(values) == 0 {
+				return nil
+			}
+			return sum / float64(len(values))
+		}
+		return sum
+	case "min", "max":
+		if len(values) == 0 {
+			return nil
+		}
+		result := values[0]
+		for _, v := range values[1:] {
+			if (field.aggregate == "min" && v < result) || (field.aggregate == "max" && v > result) {
+				result = v
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,228 @@
+package gigyatest
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gigya-module-go/accounts"
+
+	"github.com/google/uuid"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │              REGISTRATION                │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// VerifyEmail marks email as verified on the account using it as login ID,
+// as clicking the verification link does. Returns false when no account uses it.
+func (s *Server) VerifyEmail(email string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account := s.accountByLoginID(email)
+	if account == nil {
+		return false
+	}
+	verified := appendMissing(stringList(account, "emails.verified"), email)
+	var unverified []string
+	for _, e := range stringList(account, "emails.unverified") {
+		if !strings.EqualFold(e, email) {
+			unverified = append(unverified, e)
+		}
+	}
+	setStringList(account, "emails.verified", verified)
+	setStringList(account, "emails.unverified", unverified)
+	account["isVerified"] = true
+	return true
+}
+
+func (s *Server) initRegistration(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	regToken := strings.ReplaceAll(uuid.New().String(), "-", "")
+	s.regTokens[regToken] = ""
+	return map[string]interface{}{"regToken": regToken}, nil
+}
+
+func (s *Server) register(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	regToken := params.Get("regToken")
+	if regToken == "" {
+		return nil, newError(ErrorCodeMissingParameter, "regToken")
+	}
+	if UID, ok := s.regTokens[regToken]; !ok || UID != "" {
+		return nil, newError(ErrorCodeInvalidParameter, "regToken is invalid or already used")
+	}
+	email := params.Get("email")
+	if email == "" {
+		return nil, newError(ErrorCodeMissingParameter, "email")
+	}
+	if params.Get("password") == "" {
+		return nil, newError(ErrorCodeMissingParameter, "password")
+	}
+	if !emailPattern.MatchString(email) {
+		apiErr := newError(accounts.ErrorCodeValidation, "")
+		apiErr.ValidationErrors = []accounts.ValidationError{{ErrorCode: ErrorCodeInvalidParameter, Message: "Invalid email format", FieldName: "email"}}
+		return nil, apiErr
+	}
+	if existing := s.accountByLoginID(email); existing != nil {
+		return s.conflict(existing, email)
+	}
+	if apiErr := s.checkPasswordPolicy(nil, params.Get("password")); apiErr != nil {
+		return nil, apiErr
+	}
+
+	account := map[string]interface{}{
+		"isRegistered": false,
+		"isVerified":   false,
+		"isActive":     true,
+		"profile":      map[string]interface{}{"email": email},
+		"emails":       map[string]interface{}{"verified": []interface{}{}, "unverified": []interface{}{email}},
+		"loginIDs":     map[string]interface{}{"emails": []interface{}{email}},
+		"password":     hashPassword(params.Get("password")),
+	}
+	for _, key := range []string{"profile", "data", "preferences", "subscriptions"} {
+		value := params.Get(key)
+		if value == "" {
+			continue
+		}
+		var section map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &section); err != nil {
+			return nil, newError(ErrorCodeInvalidParameter, key+": "+err.Error())
+		}
+		target, _ := account[key].(map[string]interface{})
+		if target == nil {
+			target = map[string]interface{}{}
+		}
+		account[key] = mergePatch(target, section)
+	}
+	for _, key := range []string{"username", "lang", "regSource"} {
+		if value := params.Get(key); value != "" {
+			account[key] = value
+		}
+	}
+
+	UID := s.store(account, time.Now())
+	s.regTokens[regToken] = UID
+
+	response := map[string]interface{}{"UID": UID}
+	if finalize, _ := strconv.ParseBool(params.Get("finalizeRegistration")); finalize {
+		return s.finalize(regToken, account)
+	}
+	response["regToken"] = regToken
+	return response, nil
+}
+
+func (s *Server) finalizeRegistration(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	regToken := params.Get("regToken")
+	if regToken == "" {
+		return nil, newError(ErrorCodeMissingParameter, "regToken")
+	}
+	account := s.accounts[s.regTokens[regToken]]
+	if account == nil {
+		return nil, newError(ErrorCodeInvalidParameter, "regToken is invalid or expired")
+	}
+	return s.finalize(regToken, account)
+}
+
+// finalize completes the registration, unless fields are missing (206001)
+// or the email is not verified yet (206002)
+func (s *Server) finalize(regToken string, account map[string]interface{}) (map[string]interface{}, *accounts.APIError) {
+	UID := account["UID"]
+
+	var missing []string
+	for _, field := range s.RequiredFields {
+		if v, ok := lookup(account, field); !ok || v == nil || v == "" {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return pending(accounts.ErrorCodeAccountPendingRegistration, "missing required fields: "+strings.Join(missing, ", "), UID, regToken)
+	}
+	if s.RequireEmailVerification && account["isVerified"] != true {
+		return pending(accounts.ErrorCodeAccountPendingVerification, "", UID, regToken)
+	}
+
+	now := time.Now()
+	account["isRegistered"] = true
+	account["registered"] = now.UTC().Format("2006-01-02T15:04:05.000Z")
+	account["registeredTimestamp"] = float64(now.UnixMilli())
+	touch(account, now)
+	delete(s.regTokens, regToken)
+
+	return map[string]interface{}{
+		"UID":          UID,
+		"isRegistered": true,
+		"isVerified":   account["isVerified"],
+	}, nil
+}
+
+// pending returns a pending registration error whose body keeps the UID and regToken
+func pending(code int, details string, UID interface{}, regToken string) (map[string]interface{}, *accounts.APIError) {
+	return map[string]interface{}{"UID": UID, "regToken": regToken}, newError(code, details)
+}
+
+// conflict answers 403043 with a regToken giving the existing account to getConflictingAccount
+func (s *Server) conflict(existing map[string]interface{}, loginID string) (map[string]interface{}, *accounts.APIError) {
+	regToken := strings.ReplaceAll(uuid.New().String(), "-", "")
+	s.conflicts[regToken] = existing["UID"].(string)
+	return map[string]interface{}{"regToken": regToken}, newError(accounts.ErrorCodeLoginIdentifierExists, "loginID "+loginID+" is already used")
+}
+
+// accountByLoginID returns the account using email as login ID. Callers hold mu.
+func (s *Server) accountByLoginID(email string) map[string]interface{} {
+	for _, account := range s.accounts {
+		if containsFold(loginEmails(account), email) {
+			return account
+		}
+	}
+	return nil
+}
+
+// hashPassword stores a password the way CDC exposes it: hash and hash settings
+func hashPassword(password string) map[string]interface{} {
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	encodedSalt := base64.StdEncoding.EncodeToString(salt)
+	return map[string]interface{}{
+		"hashedPassword": passwordHash(encodedSalt, password),
+		"hashSettings":   map[string]interface{}{"algorithm": "sha256", "salt": encodedSalt},
+		"created":        time.Now().UTC().Format("2006-01-02T15:04:05.000Z"),
+	}
+}
+
+// checkPassword reports whether password matches the stored hash
+func checkPassword(account map[string]interface{}, password string) bool {
+	hashed, _ := lookup(account, "password.hashedPassword")
+	salt, _ := lookup(account, "password.hashSettings.salt")
+	saltText, _ := salt.(string)
+	return hashed != nil && hashed == passwordHash(saltText, password)
+}
+
+func passwordHash(salt, password string) string {
+	sum := sha256.Sum256([]byte(salt + password))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func stringList(account map[string]interface{}, path string) []string {
+	v, _ := lookup(account, path)
+	list, _ := v.([]interface{})
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		if value, ok := item.(string); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func setStringList(account map[string]interface{}, path string, values []string) {
+	list := make([]interface{}, len(values))
+	for i, value := range values {
+		list[i] = value
+	}
+	setPath(account, path, list)
+}
@@ -0,0 +1,107 @@
+package gigyatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"gigya-module-go/accounts"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │                  SCHEMA                  │ */
+/* ╰──────────────────────────────────────────╯ */
+
+var schemaSections = []string{"profileSchema", "dataSchema", "subscriptionsSchema", "preferencesSchema"}
+
+// defaultSchema is the schema of a new site: the usual profile fields and a dynamic data schema
+func defaultSchema() map[string]map[string]interface{} {
+	profileField := func() interface{} {
+		return map[string]interface{}{"type": "string", "writeAccess": "clientModify", "allowNull": true}
+	}
+	return map[string]map[string]interface{}{
+		"profileSchema": {
+			"fields": map[string]interface{}{
+				"email":     profileField(),
+				"firstName": profileField(),
+				"lastName":  profileField(),
+				"country":   profileField(),
+				"zip":       profileField(),
+				"city":      profileField(),
+			},
+		},
+		"dataSchema":          {"fields": map[string]interface{}{}, "dynamicSchema": true},
+		"subscriptionsSchema": {"fields": map[string]interface{}{}},
+		"preferencesSchema":   {"fields": map[string]interface{}{}},
+	}
+}
+
+func (s *Server) getSchema(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	response := map[string]interface{}{}
+	for _, name := range schemaSections {
+		response[name] = deepCopy(s.schema[name])
+	}
+	return response, nil
+}
+
+// setSchema merges the attributes of the fields sent into the schema. A field
+// set to null is removed; the type of an existing field cannot change.
+func (s *Server) setSchema(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	changes := map[string]map[string]interface{}{}
+	for _, name := range schemaSections {
+		if params.Get(name) == "" {
+			continue
+		}
+		var section map[string]interface{}
+		if err := json.Unmarshal([]byte(params.Get(name)), &section); err != nil {
+			return nil, newError(ErrorCodeInvalidParameter, name+" is not a JSON object")
+		}
+		changes[name] = section
+	}
+	if len(changes) == 0 {
+		return nil, newError(ErrorCodeMissingParameter, "profileSchema, dataSchema, subscriptionsSchema or preferencesSchema")
+	}
+
+	// Validate everything first, so a rejected request changes nothing
+	for name, section := range changes {
+		fields, _ := section["fields"].(map[string]interface{})
+		current := s.schema[name]["fields"].(map[string]interface{})
+		for field, value := range fields {
+			attributes, ok := value.(map[string]interface{})
+			if value != nil && !ok {
+				return nil, newError(ErrorCodeInvalidParameter, fmt.Sprintf("%s.fields.%s is not an object", name, field))
+			}
+			existing, _ := current[field].(map[string]interface{})
+			if existing == nil || attributes["type"] == nil {
+				continue
+			}
+			if attributes["type"] != existing["type"] {
+				return nil, newError(ErrorCodeInvalidParameter, fmt.Sprintf("the type of %s.fields.%s cannot change", name, field))
+			}
+		}
+	}
+
+	for name, section := range changes {
+		current := s.schema[name]
+		if dynamic, ok := section["dynamicSchema"].(bool); ok {
+			current["dynamicSchema"] = dynamic
+		}
+		fields, _ := section["fields"].(map[string]interface{})
+		currentFields := current["fields"].(map[string]interface{})
+		for field, value := range fields {
+			if value == nil {
+				delete(currentFields, field)
+				continue
+			}
+			existing, _ := currentFields[field].(map[string]interface{})
+			if existing == nil {
+				existing = map[string]interface{}{}
+				currentFields[field] = existing
+			}
+			for attribute, v := range value.(map[string]interface{}) {
+				existing[attribute] = v
+			}
+		}
+	}
+	return map[string]interface{}{}, nil
+}
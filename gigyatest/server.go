@@ -0,0 +1,838 @@
+// Package gigyatest provides an in-memory fake of the Gigya CDC accounts API,
+// to test code built on accounts.AccountsAPI without a real site:
+//
+//	srv := gigyatest.NewServer()
+//	defer srv.Close()
+//
+//	uid := srv.AddAccount(accounts.Account{Profile: accounts.Profile{Email: "jane@example.com"}})
+//	api := srv.AccountsAPI()
+//	account, err := api.GetAccountInfo(uid)
+package gigyatest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/gigya"
+
+	"github.com/google/uuid"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │              FAKE CDC SERVER             │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// Default credentials accepted by a new Server
+const (
+	DefaultAPIKey    = "test-api-key"
+	DefaultUserKey   = "test-user-key"
+	DefaultSecretKey = "dGVzdC1zZWNyZXQ=" // base64("test-secret")
+)
+
+// Error codes returned by the fake besides the ones defined in accounts
+const (
+	ErrorCodeMissingParameter = 400002
+	ErrorCodeUniqueIDExists   = 400003
+	ErrorCodeInvalidParameter = 400006
+	ErrorCodeInvalidAPIKey    = 400093
+	ErrorCodeRequestExpired   = 403002
+	ErrorCodeInvalidSignature = 403003
+	ErrorCodeLoginIDNotFound  = 403047
+)
+
+var errorMessages = map[int]string{
+	ErrorCodeMissingParameter:                    "Missing required parameter",
+	ErrorCodeUniqueIDExists:                      "Unique identifier exists",
+	ErrorCodeInvalidParameter:                    "Invalid parameter value",
+	ErrorCodeInvalidAPIKey:                       "Invalid ApiKey parameter",
+	ErrorCodeRequestExpired:                      "Request has expired",
+	ErrorCodeInvalidSignature:                    "Invalid request signature",
+	accounts.ErrorCodeInvalidLoginID:             "Invalid LoginID",
+	accounts.ErrorCodeLoginIdentifierExists:      "Login identifier exists",
+	accounts.ErrorCodeOldPasswordUsed:            "Old Password Used",
+	ErrorCodeLoginIDNotFound:                     "Login ID does not exist",
+	accounts.ErrorCodeNotFound:                   "Unauthorized user",
+	accounts.ErrorCodeValidation:                 "Schema validation failed",
+	accounts.ErrorCodeRateLimited:                "Rate limit exceeded",
+	accounts.ErrorCodeGeneralServerError:         "General Server Error",
+	accounts.ErrorCodeAccountPendingRegistration: "Account Pending Registration",
+	accounts.ErrorCodeAccountPendingVerification: "Account Pending Verification",
+}
+
+// defaultSearchLimit is the number of results returned when the query has no limit
+const defaultSearchLimit = 300
+
+// Server is a fake CDC site served over TLS by httptest. Accounts are kept in
+// memory as raw JSON objects, so any field written by a client can be read back.
+// It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	// Credentials checked on every request. They can be changed before the
+	// first request; the helpers AccountsAPI and Gigya use them.
+	APIKey    string
+	UserKey   string
+	SecretKey string
+
+	// RequiredFields are the dotted paths (e.g. "profile.firstName") a registration
+	// needs; without them register and finalizeRegistration answer 206001.
+	RequiredFields []string
+	// RequireEmailVerification makes finalizing a registration answer 206002
+	// until VerifyEmail is called
+	RequireEmailVerification bool
+	// PasswordMinLength rejects shorter new passwords with a 400009 validation
+	// error on the password field (no policy when 0)
+	PasswordMinLength int
+	// PasswordHistory rejects with 401030 a new password equal to one of the
+	// last PasswordHistory passwords of the account (no history when 0)
+	PasswordHistory int
+
+	mu       sync.Mutex
+	accounts map[string]map[string]interface{}
+	cursors  map[string]*searchCursor
+	errors   map[string][]*injectedError
+	calls    []Call
+	cursorID int
+	key      *rsa.PrivateKey
+	kid      string
+	// previousKeys are the keys replaced by RotateKey, oldest first
+	previousKeys []signingKey
+	handlers     map[string]handler
+	// regTokens maps the regTokens of pending registrations to their UID ("" before register)
+	regTokens map[string]string
+	// conflicts maps the regTokens returned with 403043 to the UID of the existing account
+	conflicts map[string]string
+	// sessions maps the session tokens and cookies to their UID
+	sessions map[string]string
+	// resetTokens maps the password reset tokens to their UID
+	resetTokens map[string]string
+	// oldPasswords keeps the previous password hashes of each UID, newest last
+	oldPasswords map[string][]map[string]interface{}
+	// schema holds the sections of the site schema, keyed by profileSchema...
+	schema map[string]map[string]interface{}
+}
+
+// Call is a request received by the server
+type Call struct {
+	Method string     // API method, e.g. "accounts.search"
+	Params url.Values // Form parameters, credentials included
+}
+
+type signingKey struct {
+	kid string
+	key *rsa.PrivateKey
+}
+
+func newKeyID() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")[:16]
+}
+
+type injectedError struct {
+	apiErr accounts.APIError
+	times  int // Remaining failures, <= 0 means until ClearErrors
+}
+
+// searchCursor keeps the results not returned yet by a search opened with openCursor
+type searchCursor struct {
+	results    []map[string]interface{}
+	totalCount int
+	pageSize   int
+}
+
+// handler serves an API method. When it returns an error, the fields of the
+// response (if any) are added to the error body, as CDC does for regToken...
+type handler struct {
+	public bool // Only the apiKey is required
+	serve  func(s *Server, params url.Values) (map[string]interface{}, *accounts.APIError)
+}
+
+// NewServer starts a fake CDC with the default credentials and no accounts.
+// Call Close when done.
+func NewServer() *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("gigyatest: generating the JWT key: %v", err))
+	}
+
+	s := &Server{
+		APIKey:    DefaultAPIKey,
+		UserKey:   DefaultUserKey,
+		SecretKey: DefaultSecretKey,
+		accounts:  map[string]map[string]interface{}{},
+		cursors:   map[string]*searchCursor{},
+		errors:    map[string][]*injectedError{},
+		regTokens: map[string]string{},
+		conflicts: map[string]string{},
+		sessions:  map[string]string{},
+
+		resetTokens:  map[string]string{},
+		oldPasswords: map[string][]map[string]interface{}{},
+		schema:       defaultSchema(),
+		key:          key,
+		kid:          newKeyID(),
+	}
+	s.handlers = map[string]handler{
+		"accounts.search":            {serve: (*Server).search},
+		"accounts.getAccountInfo":    {serve: (*Server).getAccountInfo},
+		"accounts.setAccountInfo":    {serve: (*Server).setAccountInfo},
+		"accounts.importFullAccount": {serve: (*Server).importFullAccount},
+		"accounts.deleteAccount":     {serve: (*Server).deleteAccount},
+		"accounts.getJWTPublicKey":   {public: true, serve: (*Server).getJWTPublicKey},
+		"accounts.getJWT":            {serve: (*Server).getJWT},
+
+		"accounts.initRegistration":     {serve: (*Server).initRegistration},
+		"accounts.register":             {serve: (*Server).register},
+		"accounts.finalizeRegistration": {serve: (*Server).finalizeRegistration},
+
+		"accounts.login":                 {serve: (*Server).login},
+		"accounts.notifyLogin":           {serve: (*Server).notifyLogin},
+		"accounts.logout":                {serve: (*Server).logout},
+		"accounts.verifyLogin":           {serve: (*Server).verifyLogin},
+		"accounts.getConflictingAccount": {serve: (*Server).getConflictingAccount},
+
+		"accounts.resetPassword": {serve: (*Server).resetPassword},
+
+		"accounts.getSchema": {serve: (*Server).getSchema},
+		"accounts.setSchema": {serve: (*Server).setSchema},
+	}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Domain returns the host:port to use as apiDomain
+func (s *Server) Domain() string {
+	return strings.TrimPrefix(s.URL, "https://")
+}
+
+// AccountsAPI returns a client pointed at the server, using its credentials and
+// an HTTP client that trusts its certificate. opts are applied after those.
+func (s *Server) AccountsAPI(opts ...accounts.Option) *accounts.AccountsAPI {
+	opts = append([]accounts.Option{accounts.WithHTTPClient(s.Client())}, opts...)
+	return accounts.NewAccountsAPI(s.APIKey, s.UserKey, s.SecretKey, s.Domain(), opts...)
+}
+
+// Gigya returns a Gigya client pointed at the server
+func (s *Server) Gigya(opts ...accounts.Option) *gigya.Gigya {
+	opts = append([]accounts.Option{accounts.WithHTTPClient(s.Client())}, opts...)
+	return gigya.NewGigya(s.APIKey, s.UserKey, s.SecretKey, s.Domain(), opts...)
+}
+
+/* ╭──────────────────────────────────────────╮ */
+/* │               TEST HELPERS               │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// AddAccount stores account, any value encoding to a JSON object (accounts.Account,
+// a map...). A UID is generated when it has none. Returns the UID.
+func (s *Server) AddAccount(account interface{}) string {
+	raw, err := toObject(account)
+	if err != nil {
+		panic(fmt.Sprintf("gigyatest: AddAccount: %v", err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store(raw, time.Now())
+}
+
+// Account returns a copy of the stored account
+func (s *Server) Account(UID string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.accounts[UID]
+	if !ok {
+		return nil, false
+	}
+	return deepCopy(account), true
+}
+
+// Count returns the number of stored accounts
+func (s *Server) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.accounts)
+}
+
+// InjectError makes the next times calls of method fail with errorCode
+// (every call until ClearErrors when times <= 0). Errors injected for the
+// same method are returned in order.
+func (s *Server) InjectError(method string, errorCode int, times int) {
+	s.InjectAPIError(method, accounts.APIError{ErrorCode: errorCode}, times)
+}
+
+// InjectAPIError is like InjectError with a full error body (details,
+// validation errors...). Missing status fields are filled from the code.
+func (s *Server) InjectAPIError(method string, apiErr accounts.APIError, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors[method] = append(s.errors[method], &injectedError{apiErr: apiErr, times: times})
+}
+
+// ClearErrors removes every injected error
+func (s *Server) ClearErrors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors = map[string][]*injectedError{}
+}
+
+// ExpireCursors drops every open search cursor, as CDC does after a while
+func (s *Server) ExpireCursors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cursors = map[string]*searchCursor{}
+}
+
+// Calls returns the requests received for method (every request when method is empty)
+func (s *Server) Calls(method string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var calls []Call
+	for _, call := range s.calls {
+		if method == "" || call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// PrivateKey returns the RSA key whose public part is served by accounts.getJWTPublicKey
+func (s *Server) PrivateKey() *rsa.PrivateKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.key
+}
+
+// KeyID returns the kid of the server key
+func (s *Server) KeyID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.kid
+}
+
+// RotateKey replaces the server key by a new one and returns its kid. The
+// previous keys are still listed by getJWTPublicKey with V2=true, as CDC does
+// while tokens signed with them may be valid.
+func (s *Server) RotateKey() string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("gigyatest: generating the JWT key: %v", err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.previousKeys = append(s.previousKeys, signingKey{kid: s.kid, key: s.key})
+	s.key = key
+	s.kid = newKeyID()
+	return s.kid
+}
+
+/* ╭──────────────────────────────────────────╮ */
+/* │              REQUEST HANDLING            │ */
+/* ╰──────────────────────────────────────────╯ */
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, "/")
+	if err := r.ParseForm(); err != nil {
+		writeError(w, newError(ErrorCodeInvalidParameter, err.Error()), nil)
+		return
+	}
+	params := r.Form
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, Call{Method: method, Params: params})
+
+	h, ok := s.handlers[method]
+	if !ok {
+		writeError(w, &accounts.APIError{ErrorCode: 404000, StatusCode: http.StatusNotFound, StatusReason: "Not Found", ErrorMessage: "Method not implemented by gigyatest"}, nil)
+		return
+	}
+	if apiErr := s.authenticate(r, params, h.public); apiErr != nil {
+		writeError(w, apiErr, nil)
+		return
+	}
+	if apiErr := s.injected(method); apiErr != nil {
+		writeError(w, apiErr, nil)
+		return
+	}
+
+	response, apiErr := h.serve(s, params)
+	if apiErr != nil {
+		writeError(w, apiErr, response)
+		return
+	}
+	writeJSON(w, withStatus(response, 0, http.StatusOK, "OK"))
+}
+
+// authenticate checks the apiKey and, for authenticated methods, the userKey
+// with either the secret or a valid request signature
+func (s *Server) authenticate(r *http.Request, params url.Values, public bool) *accounts.APIError {
+	if params.Get("apiKey") != s.APIKey {
+		return newError(ErrorCodeInvalidAPIKey, "")
+	}
+	if public {
+		return nil
+	}
+	if params.Get("userKey") != s.UserKey {
+		return newError(ErrorCodeInvalidSignature, "invalid userKey")
+	}
+
+	sig := params.Get("sig")
+	if sig == "" {
+		if params.Get("secret") != s.SecretKey {
+			return newError(ErrorCodeInvalidSignature, "invalid secret")
+		}
+		return nil
+	}
+
+	timestamp, err := strconv.ParseInt(params.Get("timestamp"), 10, 64)
+	if err != nil {
+		return newError(ErrorCodeInvalidParameter, "invalid timestamp")
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); age > 120*time.Second || age < -120*time.Second {
+		return newError(ErrorCodeRequestExpired, "")
+	}
+	expected, err := accounts.CalcSignature(s.SecretKey, http.MethodPost, "https://"+r.Host+r.URL.Path, params)
+	if err != nil || expected != sig {
+		return newError(ErrorCodeInvalidSignature, "signature mismatch")
+	}
+	return nil
+}
+
+// injected consumes the next injected error of method, if any
+func (s *Server) injected(method string) *accounts.APIError {
+	queue := s.errors[method]
+	if len(queue) == 0 {
+		return nil
+	}
+	next := queue[0]
+	if next.times > 0 {
+		next.times--
+		if next.times == 0 {
+			s.errors[method] = queue[1:]
+		}
+	}
+
+	apiErr := next.apiErr
+	if apiErr.StatusCode == 0 {
+		apiErr.StatusCode = apiErr.ErrorCode / 1000
+	}
+	if apiErr.ErrorMessage == "" {
+		apiErr.ErrorMessage = errorMessages[apiErr.ErrorCode]
+	}
+	return &apiErr
+}
+
+func newError(code int, details string) *accounts.APIError {
+	return &accounts.APIError{
+		ErrorCode:    code,
+		StatusCode:   code / 1000,
+		StatusReason: http.StatusText(code / 1000),
+		ErrorMessage: errorMessages[code],
+		ErrorDetails: details,
+	}
+}
+
+// writeError answers with HTTP 200 and the error in the body, as CDC does
+func writeError(w http.ResponseWriter, apiErr *accounts.APIError, response map[string]interface{}) {
+	if response == nil {
+		response = map[string]interface{}{}
+	}
+	if apiErr.ErrorMessage != "" {
+		response["errorMessage"] = apiErr.ErrorMessage
+	}
+	if apiErr.ErrorDetails != "" {
+		response["errorDetails"] = apiErr.ErrorDetails
+	}
+	if len(apiErr.ValidationErrors) > 0 {
+		response["validationErrors"] = apiErr.ValidationErrors
+	}
+	reason := apiErr.StatusReason
+	if reason == "" {
+		reason = http.StatusText(apiErr.StatusCode)
+	}
+	writeJSON(w, withStatus(response, apiErr.ErrorCode, apiErr.StatusCode, reason))
+}
+
+func withStatus(response map[string]interface{}, errorCode, statusCode int, statusReason string) map[string]interface{} {
+	if response == nil {
+		response = map[string]interface{}{}
+	}
+	response["callId"] = strings.ReplaceAll(uuid.New().String(), "-", "")
+	response["errorCode"] = errorCode
+	response["apiVersion"] = 2
+	response["statusCode"] = statusCode
+	response["statusReason"] = statusReason
+	response["time"] = time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	return response
+}
+
+func writeJSON(w http.ResponseWriter, response map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(response)
+}
+
+/* ╭──────────────────────────────────────────╮ */
+/* │                 METHODS                  │ */
+/* ╰──────────────────────────────────────────╯ */
+
+func (s *Server) search(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	if cursorID := params.Get("cursorId"); cursorID != "" {
+		cursor, ok := s.cursors[cursorID]
+		if !ok {
+			return nil, newError(ErrorCodeInvalidParameter, "cursorId is invalid or expired")
+		}
+		delete(s.cursors, cursorID)
+		return s.searchPage(cursor.results, cursor.totalCount, cursor.pageSize), nil
+	}
+
+	queryText := params.Get("query")
+	if queryText == "" {
+		return nil, newError(ErrorCodeMissingParameter, "query")
+	}
+	q, err := parseQuery(queryText)
+	if err != nil {
+		return nil, newError(ErrorCodeInvalidParameter, "query: "+err.Error())
+	}
+
+	matches := q.filter(s.sortedAccounts())
+	var results []map[string]interface{}
+	if q.isAggregate() {
+		results = q.aggregate(matches)
+	} else {
+		results = make([]map[string]interface{}, len(matches))
+		for i, account := range matches {
+			results[i] = deepCopy(q.project(account))
+		}
+	}
+	totalCount := len(results)
+
+	if q.start > 0 {
+		results = results[min(q.start, len(results)):]
+	}
+	limit := q.limit
+	if limit < 0 {
+		limit = defaultSearchLimit
+	}
+
+	if params.Get("openCursor") == "true" {
+		// With a cursor the limit is the page size
+		return s.searchPage(results, totalCount, limit), nil
+	}
+	results = results[:min(limit, len(results))]
+	return map[string]interface{}{
+		"results":      results,
+		"objectsCount": len(results),
+		"totalCount":   totalCount,
+	}, nil
+}
+
+// searchPage returns the first page of results and keeps the rest behind a new cursor
+func (s *Server) searchPage(results []map[string]interface{}, totalCount, pageSize int) map[string]interface{} {
+	page := results[:min(pageSize, len(results))]
+
+	response := map[string]interface{}{
+		"results":      page,
+		"objectsCount": len(page),
+		"totalCount":   totalCount,
+	}
+	if rest := results[len(page):]; len(rest) > 0 {
+		s.cursorID++
+		next := fmt.Sprintf("cursor-%d", s.cursorID)
+		s.cursors[next] = &searchCursor{results: rest, totalCount: totalCount, pageSize: pageSize}
+		response["nextCursorId"] = next
+	}
+	return response
+}
+
+func (s *Server) getAccountInfo(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	account, apiErr := s.lookupAccount(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	return deepCopy(account), nil
+}
+
+func (s *Server) setAccountInfo(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	account, apiErr := s.lookupAccount(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	// Validate every parameter before changing anything
+	patches := map[string]map[string]interface{}{}
+	for _, key := range []string{"profile", "data", "preferences", "subscriptions"} {
+		if value := params.Get(key); value != "" {
+			var patch map[string]interface{}
+			if err := json.Unmarshal([]byte(value), &patch); err != nil {
+				return nil, newError(ErrorCodeInvalidParameter, key+": "+err.Error())
+			}
+			patches[key] = patch
+		}
+	}
+	flags := map[string]bool{}
+	for _, key := range []string{"isVerified", "isActive", "isRegistered"} {
+		if value := params.Get(key); value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, newError(ErrorCodeInvalidParameter, key)
+			}
+			flags[key] = b
+		}
+	}
+
+	newPassword := params.Get("newPassword")
+	if newPassword != "" {
+		if params.Get("securityOverride") != "true" && !checkPassword(account, params.Get("password")) {
+			return nil, newError(accounts.ErrorCodeInvalidLoginID, "invalid current password")
+		}
+		if apiErr := s.checkPasswordPolicy(account, newPassword); apiErr != nil {
+			return nil, apiErr
+		}
+	}
+
+	if newPassword != "" {
+		s.setPassword(account, newPassword)
+	}
+	for key, patch := range patches {
+		target, _ := account[key].(map[string]interface{})
+		if target == nil {
+			target = map[string]interface{}{}
+		}
+		account[key] = mergePatch(target, patch)
+	}
+	for key, b := range flags {
+		account[key] = b
+	}
+	for _, key := range []string{"username", "lang"} {
+		if value, ok := params[key]; ok {
+			account[key] = value[0]
+		}
+	}
+	if add := splitList(params.Get("addLoginEmails")); len(add) > 0 {
+		setLoginEmails(account, appendMissing(loginEmails(account), add...))
+	}
+	if remove := splitList(params.Get("removeLoginEmails")); len(remove) > 0 {
+		var kept []string
+		for _, email := range loginEmails(account) {
+			if !containsFold(remove, email) {
+				kept = append(kept, email)
+			}
+		}
+		setLoginEmails(account, kept)
+	}
+
+	touch(account, time.Now())
+	return map[string]interface{}{"UID": account["UID"]}, nil
+}
+
+func (s *Server) importFullAccount(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	value := params.Get("account")
+	if value == "" {
+		return nil, newError(ErrorCodeMissingParameter, "account")
+	}
+	var account map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &account); err != nil {
+		return nil, newError(ErrorCodeInvalidParameter, "account: "+err.Error())
+	}
+
+	policy := params.Get("importPolicy")
+	if policy == "" {
+		policy = "insert"
+	}
+	if policy != "insert" && policy != "upsert" {
+		return nil, newError(ErrorCodeInvalidParameter, "importPolicy")
+	}
+
+	UID, _ := account["UID"].(string)
+	if existing, ok := s.accounts[UID]; ok && UID != "" {
+		if policy == "insert" {
+			return nil, newError(ErrorCodeUniqueIDExists, "UID "+UID+" already exists")
+		}
+		mergePatch(existing, account)
+		touch(existing, time.Now())
+		return map[string]interface{}{"UID": UID}, nil
+	}
+	return map[string]interface{}{"UID": s.store(account, time.Now())}, nil
+}
+
+func (s *Server) deleteAccount(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	account, apiErr := s.lookupAccount(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	UID := account["UID"].(string)
+	delete(s.accounts, UID)
+	return map[string]interface{}{"UID": UID}, nil
+}
+
+// getJWTPublicKey returns the current key, or with V2=true the JWKS of every key
+func (s *Server) getJWTPublicKey(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	if v2, _ := strconv.ParseBool(params.Get("V2")); v2 {
+		keys := []interface{}{}
+		for _, previous := range s.previousKeys {
+			keys = append(keys, publicJWK(previous.kid, previous.key))
+		}
+		keys = append(keys, publicJWK(s.kid, s.key))
+		return map[string]interface{}{"keys": keys}, nil
+	}
+	return publicJWK(s.kid, s.key), nil
+}
+
+// publicJWK returns the public part of key as a JWK
+func publicJWK(kid string, key *rsa.PrivateKey) map[string]interface{} {
+	return map[string]interface{}{
+		"alg": "RS256",
+		"kty": "RSA",
+		"use": "sig",
+		"kid": kid,
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+/* ╭──────────────────────────────────────────╮ */
+/* │                 STORAGE                  │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// store saves account, generating the UID and the system fields it lacks. Callers hold mu.
+func (s *Server) store(account map[string]interface{}, now time.Time) string {
+	UID, _ := account["UID"].(string)
+	if UID == "" {
+		UID = strings.ReplaceAll(uuid.New().String(), "-", "")
+		account["UID"] = UID
+	}
+	if _, ok := account["created"]; !ok {
+		account["created"] = now.UTC().Format("2006-01-02T15:04:05.000Z")
+		account["createdTimestamp"] = float64(now.UnixMilli())
+	}
+	if _, ok := account["lastUpdated"]; !ok {
+		touch(account, now)
+	}
+	s.accounts[UID] = account
+	return UID
+}
+
+func (s *Server) lookupAccount(params url.Values) (map[string]interface{}, *accounts.APIError) {
+	UID := params.Get("UID")
+	if UID == "" && params.Get("regToken") != "" {
+		// Pending registrations are completed with the regToken instead of the UID
+		UID = s.regTokens[params.Get("regToken")]
+	}
+	if UID == "" {
+		return nil, newError(ErrorCodeMissingParameter, "UID")
+	}
+	account, ok := s.accounts[UID]
+	if !ok {
+		return nil, newError(accounts.ErrorCodeNotFound, "account "+UID+" not found")
+	}
+	return account, nil
+}
+
+// sortedAccounts returns the accounts ordered by UID, so results are stable
+func (s *Server) sortedAccounts() []map[string]interface{} {
+	UIDs := make([]string, 0, len(s.accounts))
+	for UID := range s.accounts {
+		UIDs = append(UIDs, UID)
+	}
+	sort.Strings(UIDs)
+
+	all := make([]map[string]interface{}, len(UIDs))
+	for i, UID := range UIDs {
+		all[i] = s.accounts[UID]
+	}
+	return all
+}
+
+func touch(account map[string]interface{}, now time.Time) {
+	account["lastUpdated"] = now.UTC().Format("2006-01-02T15:04:05.000Z")
+	account["lastUpdatedTimestamp"] = float64(now.UnixMilli())
+}
+
+// mergePatch applies patch to target as a JSON merge patch: objects are merged
+// recursively and null values delete the field
+func mergePatch(target, patch map[string]interface{}) map[string]interface{} {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		if patchObject, ok := value.(map[string]interface{}); ok {
+			targetObject, ok := target[key].(map[string]interface{})
+			if !ok {
+				targetObject = map[string]interface{}{}
+			}
+			target[key] = mergePatch(targetObject, patchObject)
+			continue
+		}
+		target[key] = value
+	}
+	return target
+}
+
+func loginEmails(account map[string]interface{}) []string {
+	return stringList(account, "loginIDs.emails")
+}
+
+func setLoginEmails(account map[string]interface{}, emails []string) {
+	setStringList(account, "loginIDs.emails", emails)
+}
+
+func appendMissing(list []string, values ...string) []string {
+	for _, value := range values {
+		if !containsFold(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func toObject(v interface{}) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(encoded, &object); err != nil {
+		return nil, fmt.Errorf("%T does not encode to a JSON object", v)
+	}
+	return object, nil
+}
+
+func deepCopy(object map[string]interface{}) map[string]interface{} {
+	copied, _ := toObject(object)
+	return copied
+}
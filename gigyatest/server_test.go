@@ -0,0 +1,195 @@
+package gigyatest_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/gigyatest"
+)
+
+// post sends a raw request to the server and returns the decoded response
+func post(t *testing.T, srv *gigyatest.Server, method string, params url.Values) map[string]interface{} {
+	t.Helper()
+	resp, err := srv.Client().PostForm(srv.URL+"/"+method, params)
+	if err != nil {
+		t.Fatalf("POST %s: %v", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST %s: HTTP %d", method, resp.StatusCode)
+	}
+	var response map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("POST %s: invalid response: %v", method, err)
+	}
+	return response
+}
+
+// credentials returns the parameters of a request authenticated with the secret
+func credentials(srv *gigyatest.Server) url.Values {
+	return url.Values{"apiKey": {srv.APIKey}, "userKey": {srv.UserKey}, "secret": {srv.SecretKey}}
+}
+
+// errorCode returns the errorCode of a response
+func errorCode(response map[string]interface{}) int {
+	code, _ := response["errorCode"].(float64)
+	return int(code)
+}
+
+func TestSearchCursors(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	for i := 0; i < 25; i++ {
+		srv.AddAccount(map[string]interface{}{"UID": fmt.Sprintf("uid-%03d", i)})
+	}
+
+	params := credentials(srv)
+	params.Set("query", "select UID from accounts order by UID limit 10")
+	params.Set("openCursor", "true")
+	first := post(t, srv, "accounts.search", params)
+	cursor, _ := first["nextCursorId"].(string)
+	if errorCode(first) != 0 || cursor == "" || len(first["results"].([]interface{})) != 10 || first["totalCount"] != float64(25) {
+		t.Fatalf("first page = %v", first)
+	}
+
+	next := credentials(srv)
+	next.Set("cursorId", cursor)
+	second := post(t, srv, "accounts.search", next)
+	if errorCode(second) != 0 || len(second["results"].([]interface{})) != 10 || second["nextCursorId"] == nil {
+		t.Fatalf("second page = %v", second)
+	}
+
+	// A cursor is used once
+	if code := errorCode(post(t, srv, "accounts.search", next)); code != gigyatest.ErrorCodeInvalidParameter {
+		t.Errorf("reused cursor errorCode = %d, want %d", code, gigyatest.ErrorCodeInvalidParameter)
+	}
+
+	// Expired cursors are rejected
+	srv.ExpireCursors()
+	next.Set("cursorId", second["nextCursorId"].(string))
+	expired := post(t, srv, "accounts.search", next)
+	if errorCode(expired) != gigyatest.ErrorCodeInvalidParameter || expired["errorDetails"] != "cursorId is invalid or expired" {
+		t.Errorf("expired cursor response = %v", expired)
+	}
+}
+
+func TestInjectedErrors(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	uid := srv.AddAccount(map[string]interface{}{"profile": map[string]interface{}{"email": "jane@example.com"}})
+	params := credentials(srv)
+	params.Set("UID", uid)
+
+	codes := func(method string, n int) []int {
+		t.Helper()
+		var codes []int
+		for i := 0; i < n; i++ {
+			codes = append(codes, errorCode(post(t, srv, method, params)))
+		}
+		return codes
+	}
+
+	// Injected errors are returned in order, the given number of times
+	srv.InjectError("accounts.getAccountInfo", accounts.ErrorCodeRateLimited, 2)
+	srv.InjectError("accounts.getAccountInfo", accounts.ErrorCodeGeneralServerError, 1)
+	if got := fmt.Sprint(codes("accounts.getAccountInfo", 4)); got != "[403048 403048 500001 0]" {
+		t.Errorf("errorCodes = %s, want [403048 403048 500001 0]", got)
+	}
+
+	// times <= 0 fails every call until ClearErrors
+	srv.InjectError("accounts.getAccountInfo", accounts.ErrorCodeNotFound, 0)
+	if got := fmt.Sprint(codes("accounts.getAccountInfo", 3)); got != "[403005 403005 403005]" {
+		t.Errorf("errorCodes = %s, want 403005 every time", got)
+	}
+	srv.ClearErrors()
+	if got := codes("accounts.getAccountInfo", 1); got[0] != 0 {
+		t.Errorf("errorCode after ClearErrors = %d", got[0])
+	}
+
+	// A full error body is returned as is, with the missing status filled in
+	srv.InjectAPIError("accounts.setAccountInfo", accounts.APIError{
+		ErrorCode:        accounts.ErrorCodeValidation,
+		ErrorDetails:     "profile.zip is invalid",
+		ValidationErrors: []accounts.ValidationError{{ErrorCode: 400006, Message: "invalid zip", FieldName: "profile.zip"}},
+	}, 1)
+	err := srv.AccountsAPI().UpdateAccountInfo(uid, accounts.AccountUpdate{Profile: accounts.FieldPatch{"zip": "x"}})
+	var apiErr *accounts.APIError
+	if !accounts.IsValidationError(err) || !errors.As(err, &apiErr) {
+		t.Fatalf("UpdateAccountInfo error = %v, want the injected validation error", err)
+	}
+	if apiErr.StatusCode != 400 || apiErr.ErrorDetails != "profile.zip is invalid" || len(apiErr.ValidationErrors) != 1 || apiErr.ValidationErrors[0].FieldName != "profile.zip" {
+		t.Errorf("injected error = %+v", apiErr)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	uid := srv.AddAccount(map[string]interface{}{})
+
+	// signed returns the parameters of a request signed with secret at time at
+	signed := func(secret string, at time.Time) url.Values {
+		params := url.Values{
+			"apiKey":    {srv.APIKey},
+			"userKey":   {srv.UserKey},
+			"UID":       {uid},
+			"timestamp": {strconv.FormatInt(at.Unix(), 10)},
+			"nonce":     {"nonce-1"},
+		}
+		sig, err := accounts.CalcSignature(secret, http.MethodPost, "https://"+srv.Domain()+"/accounts.getAccountInfo", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		params.Set("sig", sig)
+		return params
+	}
+	with := func(key, value string) url.Values {
+		params := credentials(srv)
+		params.Set("UID", uid)
+		params.Set(key, value)
+		return params
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		params   url.Values
+		wantCode int
+	}{
+		{name: "secret", method: "accounts.getAccountInfo", params: with("UID", uid)},
+		{name: "wrong apiKey", method: "accounts.getAccountInfo", params: with("apiKey", "other"), wantCode: gigyatest.ErrorCodeInvalidAPIKey},
+		{name: "wrong userKey", method: "accounts.getAccountInfo", params: with("userKey", "other"), wantCode: gigyatest.ErrorCodeInvalidSignature},
+		{name: "wrong secret", method: "accounts.getAccountInfo", params: with("secret", "b3RoZXI="), wantCode: gigyatest.ErrorCodeInvalidSignature},
+		{name: "signature", method: "accounts.getAccountInfo", params: signed(srv.SecretKey, time.Now())},
+		{name: "signature with the wrong secret", method: "accounts.getAccountInfo", params: signed("b3RoZXI=", time.Now()), wantCode: gigyatest.ErrorCodeInvalidSignature},
+		{name: "expired signature", method: "accounts.getAccountInfo", params: signed(srv.SecretKey, time.Now().Add(-5*time.Minute)), wantCode: gigyatest.ErrorCodeRequestExpired},
+		{name: "public method", method: "accounts.getJWTPublicKey", params: url.Values{"apiKey": {srv.APIKey}}},
+		{name: "unknown method", method: "accounts.unknown", params: with("UID", uid), wantCode: 404000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := errorCode(post(t, srv, tt.method, tt.params)); code != tt.wantCode {
+				t.Errorf("errorCode = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+
+	// Signature checked against a tampered parameter
+	tampered := signed(srv.SecretKey, time.Now())
+	tampered.Set("UID", "uid-other")
+	if code := errorCode(post(t, srv, "accounts.getAccountInfo", tampered)); code != gigyatest.ErrorCodeInvalidSignature {
+		t.Errorf("tampered request errorCode = %d, want %d", code, gigyatest.ErrorCodeInvalidSignature)
+	}
+
+	if calls := srv.Calls("accounts.getAccountInfo"); len(calls) != 8 {
+		t.Errorf("recorded %d getAccountInfo calls, want 8", len(calls))
+	}
+}
@@ -0,0 +1,85 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gigya-module-go/accounts"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │            GIGYA ID_TOKEN CLAIMS          │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// GigyaIDTokenClaims are the claims of a Gigya id_token. Profile and Data are
+// set when the token was issued with fields (getJWT fields parameter), whether
+// the claims are nested ("profile": {...}) or dotted ("profile.firstName").
+type GigyaIDTokenClaims struct {
+	Claims
+	APIKey  string            `json:"apiKey"`
+	Email   string            `json:"email,omitempty"`
+	Profile *accounts.Profile `json:"profile,omitempty"`
+	Data    *accounts.Data    `json:"data,omitempty"`
+}
+
+// UID returns the UID of the account the token was issued for (sub)
+func (c GigyaIDTokenClaims) UID() string {
+	return c.Subject
+}
+
+// VerifyIDToken verifies token like Verify, requires a sub (the UID) and
+// returns the typed claims
+func (v *Verifier) VerifyIDToken(ctx context.Context, token string) (*GigyaIDTokenClaims, error) {
+	claims, err := v.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, ErrMissingSubject
+	}
+
+	encoded, err := json.Marshal(nestClaims(claims.Raw))
+	if err != nil {
+		return nil, err
+	}
+	var idToken GigyaIDTokenClaims
+	if err := json.Unmarshal(encoded, &idToken); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrMalformedToken, err)
+	}
+	idToken.Claims = *claims
+	return &idToken, nil
+}
+
+// nestClaims moves the dotted profile and data claims into nested objects
+func nestClaims(raw map[string]interface{}) map[string]interface{} {
+	nested := make(map[string]interface{}, len(raw))
+	for key, value := range raw {
+		nested[key] = value
+	}
+	for key, value := range raw {
+		section, path, dotted := strings.Cut(key, ".")
+		if !dotted || (section != "profile" && section != "data") {
+			continue
+		}
+		delete(nested, key)
+
+		object, _ := nested[section].(map[string]interface{})
+		if object == nil {
+			object = map[string]interface{}{}
+			nested[section] = object
+		}
+		keys := strings.Split(path, ".")
+		for _, k := range keys[:len(keys)-1] {
+			child, _ := object[k].(map[string]interface{})
+			if child == nil {
+				child = map[string]interface{}{}
+				object[k] = child
+			}
+			object = child
+		}
+		object[keys[len(keys)-1]] = value
+	}
+	return nested
+}
@@ -0,0 +1,70 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"gigya-module-go/accounts"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │               KEY SOURCES                │ */
+/* ╰──────────────────────────────────────────╯ */
+
+// KeySource gets the public keys a site signs its id_tokens with
+type KeySource interface {
+	Keys(ctx context.Context) ([]accounts.JWTPublicKey, error)
+}
+
+// KeySourceFunc adapts a function to a KeySource
+type KeySourceFunc func(ctx context.Context) ([]accounts.JWTPublicKey, error)
+
+// Keys calls f
+func (f KeySourceFunc) Keys(ctx context.Context) ([]accounts.JWTPublicKey, error) {
+	return f(ctx)
+}
+
+// AccountsKeySource gets the keys with accounts.getJWTPublicKey (V2=true)
+func AccountsKeySource(api *accounts.AccountsAPI) KeySource {
+	return KeySourceFunc(api.GetJWTPublicKeysContext)
+}
+
+// URLKeySource gets the keys from a JWKS endpoint, such as
+// https://accounts.<data center>.gigya.com/accounts.getJWTPublicKey?V2=true&apiKey=<apiKey>
+// (http.DefaultClient is used when client is nil)
+func URLKeySource(url string, client *http.Client) KeySource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return KeySourceFunc(func(ctx context.Context) ([]accounts.JWTPublicKey, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching JWKS from %s: HTTP %d", url, resp.StatusCode)
+		}
+
+		var jwks accounts.GetJWTPublicKeysResponse
+		if err := json.Unmarshal(body, &jwks); err != nil {
+			return nil, fmt.Errorf("invalid JWKS from %s: %w", url, err)
+		}
+		if jwks.ErrorCode != 0 {
+			return nil, fmt.Errorf("fetching JWKS from %s: error %d", url, jwks.ErrorCode)
+		}
+		return jwks.Keys, nil
+	})
+}
@@ -0,0 +1,427 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	_ "crypto/sha256" // SHA-256 for RS256
+	_ "crypto/sha512" // SHA-384 and SHA-512 for RS384 and RS512
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/* ╭──────────────────────────────────────────╮ */
+/* │                 VERIFIER                 │ */
+/* ╰──────────────────────────────────────────╯ */
+
+const (
+	// DefaultCacheTTL is how long the keys are used before they are fetched again
+	DefaultCacheTTL = time.Hour
+	// DefaultMinRefreshInterval is the minimum time between two fetches of the keys,
+	// so tokens with unknown kids cannot flood the key source
+	DefaultMinRefreshInterval = 30 * time.Second
+)
+
+var (
+	// ErrMalformedToken is returned for tokens that are not a JWS compact serialization
+	ErrMalformedToken = errors.New("malformed token")
+	// ErrUnsupportedAlgorithm is returned for tokens signed with an algorithm not
+	// allowed (none and the HMAC algorithms never are)
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	// ErrUnknownKey is returned when no key of the site matches the kid of the token
+	ErrUnknownKey = errors.New("unknown signing key")
+	// ErrInvalidSignature is returned when the signature does not match
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrTokenExpired is returned when the exp claim is in the past
+	ErrTokenExpired = errors.New("token expired")
+	// ErrMissingExpiry is returned when the token has no exp claim, as Gigya
+	// id_tokens always have one
+	ErrMissingExpiry = errors.New("missing exp claim")
+	// ErrTokenNotYetValid is returned when the nbf or iat claim is in the future
+	ErrTokenNotYetValid = errors.New("token not valid yet")
+	// ErrTokenTooOld is returned when the token was issued longer than the maximum age ago
+	ErrTokenTooOld = errors.New("token too old")
+	// ErrInvalidIssuer is returned when the iss claim is not the expected issuer
+	ErrInvalidIssuer = errors.New("invalid issuer")
+	// ErrInvalidAPIKey is returned when the apiKey claim is not the expected API key
+	ErrInvalidAPIKey = errors.New("invalid apiKey")
+	// ErrMissingSubject is returned by VerifyIDToken when the token has no sub (UID)
+	ErrMissingSubject = errors.New("missing subject")
+)
+
+// signingHashes are the algorithms a Verifier can check, with their hash
+var signingHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+}
+
+// GigyaIssuer returns the iss claim of the id_tokens of a site
+func GigyaIssuer(apiKey string) string {
+	return "https://fidm.gigya.com/jwt/" + apiKey + "/"
+}
+
+// Claims are the verified claims of a token
+type Claims struct {
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub,omitempty"` // The UID for Gigya id_tokens
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+
+	// Raw holds every claim of the payload
+	Raw map[string]interface{} `json:"-"`
+}
+
+// Decode decodes the claims into target, a struct whose json tags are the claim names
+func (c Claims) Decode(target interface{}) error {
+	encoded, err := json.Marshal(c.Raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, target)
+}
+
+// Verifier verifies id_tokens with the keys of a site, picked by the kid of the
+// token header. Keys are cached for the cache TTL and fetched again when a token
+// has an unknown kid (the site rotated its keys). A Verifier is safe for
+// concurrent use.
+//
+//	verifier := jwt.NewVerifier(jwt.AccountsKeySource(gigyaClient.AccountsAPI))
+//	claims, err := verifier.Verify(ctx, idToken)
+type Verifier struct {
+	source             KeySource
+	cacheTTL           time.Duration
+	minRefreshInterval time.Duration
+	issuer             string
+	apiKey             string
+	clockSkew          time.Duration
+	maxAge             time.Duration
+	algorithms         map[string]bool
+	now                func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]publicKey
+	fetchedAt   time.Time // Last successful fetch
+	attemptedAt time.Time // Last fetch, successful or not
+	fetching    *keyFetch // Fetch in progress, shared by the callers needing the keys
+}
+
+// keyFetch is a fetch of the keys; err is set before done is closed
+type keyFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// VerifierOption configures a Verifier
+type VerifierOption func(*Verifier)
+
+// WithCacheTTL sets how long the keys are used before they are fetched again
+func WithCacheTTL(ttl time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.cacheTTL = ttl
+	}
+}
+
+// WithMinRefreshInterval sets the minimum time between two fetches of the keys
+func WithMinRefreshInterval(interval time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.minRefreshInterval = interval
+	}
+}
+
+// WithAPIKey only accepts the tokens of the site: the apiKey claim must be apiKey
+// and, unless WithIssuer is set, the iss claim must be GigyaIssuer(apiKey)
+func WithAPIKey(apiKey string) VerifierOption {
+	return func(v *Verifier) {
+		v.apiKey = apiKey
+	}
+}
+
+// WithIssuer only accepts the tokens whose iss claim is issuer
+func WithIssuer(issuer string) VerifierOption {
+	return func(v *Verifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithClockSkew tolerates clocks off by up to skew when checking exp, nbf, iat and the maximum age
+func WithClockSkew(skew time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.clockSkew = skew
+	}
+}
+
+// WithMaxAge rejects the tokens issued (iat) longer than maxAge ago, and those without iat
+func WithMaxAge(maxAge time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.maxAge = maxAge
+	}
+}
+
+// WithAlgorithms sets the signing algorithms accepted, among RS256, RS384 and
+// RS512 (default RS256 only). Other algorithms, none and HS256 included, are ignored.
+func WithAlgorithms(algorithms ...string) VerifierOption {
+	return func(v *Verifier) {
+		v.algorithms = map[string]bool{}
+		for _, alg := range algorithms {
+			if _, ok := signingHashes[alg]; ok {
+				v.algorithms[alg] = true
+			}
+		}
+	}
+}
+
+// WithClock sets the clock the time claims are checked against (time.Now by default)
+func WithClock(now func() time.Time) VerifierOption {
+	return func(v *Verifier) {
+		v.now = now
+	}
+}
+
+// NewVerifier creates a verifier getting its keys from source
+func NewVerifier(source KeySource, opts ...VerifierOption) *Verifier {
+	v := &Verifier{
+		source:             source,
+		cacheTTL:           DefaultCacheTTL,
+		minRefreshInterval: DefaultMinRefreshInterval,
+		algorithms:         map[string]bool{"RS256": true},
+		now:                time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	if v.issuer == "" && v.apiKey != "" {
+		v.issuer = GigyaIssuer(v.apiKey)
+	}
+	return v
+}
+
+// APIKey returns the apiKey the tokens must have, empty when not checked
+func (v *Verifier) APIKey() string {
+	return v.apiKey
+}
+
+// Issuer returns the iss the tokens must have, empty when not checked
+func (v *Verifier) Issuer() string {
+	return v.issuer
+}
+
+// publicKey is a cached key, with the algorithm the JWK restricts it to (if any)
+type publicKey struct {
+	key *rsa.PublicKey
+	alg string
+}
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// Verify checks the signature and the claims of token and returns them:
+//   - alg must be allowed (WithAlgorithms) and match the alg of the key, if set
+//   - exp is required; exp and nbf, and iat when set, must be valid now (with WithClockSkew)
+//   - iat must be within WithMaxAge, when set
+//   - iss and apiKey must match WithIssuer and WithAPIKey, when set
+//
+// Errors wrap ErrMalformedToken, ErrUnsupportedAlgorithm, ErrUnknownKey,
+// ErrInvalidSignature, ErrMissingExpiry, ErrTokenExpired, ErrTokenNotYetValid,
+// ErrTokenTooOld, ErrInvalidIssuer or ErrInvalidAPIKey, or are those of the key
+// source.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrMalformedToken, err)
+	}
+	hash, known := signingHashes[header.Alg]
+	if !known || !v.algorithms[header.Alg] {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, header.Alg)
+	}
+
+	signature, err := sanitizeAndBase64Decode(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrMalformedToken, err)
+	}
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	// A key published for one algorithm is never used with another
+	if key.alg != "" && key.alg != header.Alg {
+		return nil, fmt.Errorf("%w: %q with a %s key", ErrUnsupportedAlgorithm, header.Alg, key.alg)
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key.key, hash, hasher.Sum(nil), signature); err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrMalformedToken, err)
+	}
+	if err := decodeSegment(parts[1], &claims.Raw); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrMalformedToken, err)
+	}
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// validate checks the time, issuer and apiKey claims
+func (v *Verifier) validate(claims Claims) error {
+	now := v.now()
+	skew := v.clockSkew
+
+	if claims.ExpiresAt == 0 {
+		return ErrMissingExpiry
+	}
+	if !now.Before(time.Unix(claims.ExpiresAt, 0).Add(skew)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != 0 && now.Add(skew).Before(time.Unix(claims.NotBefore, 0)) {
+		return ErrTokenNotYetValid
+	}
+	if claims.IssuedAt != 0 && now.Add(skew).Before(time.Unix(claims.IssuedAt, 0)) {
+		return fmt.Errorf("%w: issued in the future", ErrTokenNotYetValid)
+	}
+	if v.maxAge > 0 {
+		if claims.IssuedAt == 0 {
+			return fmt.Errorf("%w: no iat claim", ErrTokenTooOld)
+		}
+		if now.Sub(time.Unix(claims.IssuedAt, 0)) > v.maxAge+skew {
+			return ErrTokenTooOld
+		}
+	}
+
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return fmt.Errorf("%w: %q", ErrInvalidIssuer, claims.Issuer)
+	}
+	if v.apiKey != "" {
+		if apiKey, _ := claims.Raw["apiKey"].(string); apiKey != v.apiKey {
+			return fmt.Errorf("%w: %q", ErrInvalidAPIKey, apiKey)
+		}
+	}
+	return nil
+}
+
+// key returns the key with the given kid, fetching the keys when the cache
+// expired or the kid is unknown. Once the cache expired, the cached keys are
+// still used while a fetch failed less than the minimum refresh interval ago.
+func (v *Verifier) key(ctx context.Context, kid string) (publicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	expired := time.Since(v.fetchedAt) >= v.cacheTTL
+	if v.keys == nil || (expired && time.Since(v.attemptedAt) >= v.minRefreshInterval) {
+		if err := v.refresh(ctx); err != nil {
+			if v.keys == nil {
+				return publicKey{}, err
+			}
+			log.Warnf("jwt: refreshing the signing keys failed, using the cached ones: %v", err)
+		}
+	}
+
+	if key, ok := v.lookup(kid); ok {
+		return key, nil
+	}
+	// Unknown kid: the site may have rotated its keys
+	if time.Since(v.attemptedAt) >= v.minRefreshInterval {
+		if err := v.refresh(ctx); err != nil {
+			return publicKey{}, err
+		}
+		if key, ok := v.lookup(kid); ok {
+			return key, nil
+		}
+	}
+	return publicKey{}, fmt.Errorf("%w: kid %q", ErrUnknownKey, kid)
+}
+
+// lookup finds a cached key; a token without kid matches the only key of the site
+func (v *Verifier) lookup(kid string) (publicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+// refresh fetches the keys, or waits for the fetch in progress. Callers hold mu,
+// which is released while fetching so the other tokens are verified meanwhile.
+func (v *Verifier) refresh(ctx context.Context) error {
+	if fetch := v.fetching; fetch != nil {
+		v.mu.Unlock()
+		defer v.mu.Lock()
+		select {
+		case <-fetch.done:
+			return fetch.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	fetch := &keyFetch{done: make(chan struct{})}
+	v.fetching = fetch
+	v.attemptedAt = time.Now()
+	v.mu.Unlock()
+	keys, err := v.fetch(ctx)
+	v.mu.Lock()
+
+	if err == nil {
+		v.keys = keys
+		v.fetchedAt = time.Now()
+	}
+	v.fetching = nil
+	fetch.err = err
+	close(fetch.done)
+	return err
+}
+
+// fetch gets the keys from the source
+func (v *Verifier) fetch(ctx context.Context) (map[string]publicKey, error) {
+	jwks, err := v.source.Keys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching the signing keys: %w", err)
+	}
+
+	keys := map[string]publicKey{}
+	for _, jwk := range jwks {
+		if jwk.Kty != "" && jwk.Kty != "RSA" {
+			continue
+		}
+		key, err := rsaPublicKey(jwk.N, jwk.E)
+		if err != nil {
+			log.Warnf("jwt: skipping invalid key %q: %v", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = publicKey{key: key, alg: jwk.Alg}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("fetching the signing keys: no RSA key")
+	}
+	return keys, nil
+}
+
+// decodeSegment decodes a base64url JSON segment of a token
+func decodeSegment(segment string, target interface{}) error {
+	decoded, err := sanitizeAndBase64Decode(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(decoded, target)
+}
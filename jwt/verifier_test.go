@@ -0,0 +1,386 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gigya-module-go/accounts"
+	"gigya-module-go/gigyatest"
+	"gigya-module-go/jwt"
+)
+
+// jwkOf returns the public JWK of key, restricted to alg when not empty
+func jwkOf(kid, alg string, key *rsa.PrivateKey) accounts.JWTPublicKey {
+	return accounts.JWTPublicKey{
+		Alg: alg,
+		Kty: "RSA",
+		Use: "sig",
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func TestVerifyAlgorithms(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	api := srv.AccountsAPI()
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A key source whose key has no alg, so the allow-list alone decides
+	anyAlg := jwt.KeySourceFunc(func(ctx context.Context) ([]accounts.JWTPublicKey, error) {
+		return []accounts.JWTPublicKey{jwkOf(srv.KeyID(), "", srv.PrivateKey())}, nil
+	})
+
+	tests := []struct {
+		name       string
+		source     jwt.KeySource
+		algorithms []string
+		alg        string
+		key        *rsa.PrivateKey
+		kid        string
+		wantErr    error
+	}{
+		{name: "RS256", alg: "RS256"},
+		{name: "none", alg: "none", wantErr: jwt.ErrUnsupportedAlgorithm},
+		{name: "HS256 with the public key", alg: "HS256", wantErr: jwt.ErrUnsupportedAlgorithm},
+		{name: "HS256 allowed explicitly", algorithms: []string{"RS256", "HS256"}, alg: "HS256", wantErr: jwt.ErrUnsupportedAlgorithm},
+		{name: "RS384 not allowed", alg: "RS384", wantErr: jwt.ErrUnsupportedAlgorithm},
+		{name: "RS384 with an RS256 key", algorithms: []string{"RS256", "RS384"}, alg: "RS384", wantErr: jwt.ErrUnsupportedAlgorithm},
+		{name: "RS384 with a key without alg", source: anyAlg, algorithms: []string{"RS384"}, alg: "RS384"},
+		{name: "RS256 not allowed", source: anyAlg, algorithms: []string{"RS384"}, alg: "RS256", wantErr: jwt.ErrUnsupportedAlgorithm},
+		{name: "signed with another key", alg: "RS256", key: other, wantErr: jwt.ErrInvalidSignature},
+		{name: "unknown kid", alg: "RS256", key: other, kid: "unknown", wantErr: jwt.ErrUnknownKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := tt.source
+			if source == nil {
+				source = jwt.AccountsKeySource(api)
+			}
+			var opts []jwt.VerifierOption
+			if tt.algorithms != nil {
+				opts = append(opts, jwt.WithAlgorithms(tt.algorithms...))
+			}
+			key, kid := tt.key, tt.kid
+			if key == nil {
+				key = srv.PrivateKey()
+			}
+			if kid == "" {
+				kid = srv.KeyID()
+			}
+
+			token := gigyatest.SignToken(key, tt.alg, kid, srv.IDTokenClaims("uid-1"))
+			claims, err := jwt.NewVerifier(source, opts...).Verify(context.Background(), token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && claims.Subject != "uid-1" {
+				t.Errorf("Subject = %q, want uid-1", claims.Subject)
+			}
+		})
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	verifier := jwt.NewVerifier(jwt.AccountsKeySource(srv.AccountsAPI()))
+	valid := srv.IDToken(srv.IDTokenClaims("uid-1"))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "empty", token: ""},
+		{name: "two segments", token: "a.b"},
+		{name: "four segments", token: valid + ".x"},
+		{name: "header not base64", token: "%%%.e30.sig"},
+		{name: "header not JSON", token: base64.RawURLEncoding.EncodeToString([]byte("nope")) + ".e30.sig"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifier.Verify(context.Background(), tt.token); !errors.Is(err, jwt.ErrMalformedToken) {
+				t.Errorf("Verify error = %v, want %v", err, jwt.ErrMalformedToken)
+			}
+		})
+	}
+}
+
+func TestVerifyKeyRotation(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	api := srv.AccountsAPI()
+
+	tests := []struct {
+		name        string
+		opts        []jwt.VerifierOption
+		wantFetches int
+		wantErr     error
+	}{
+		{name: "unknown kid refetches the keys", opts: []jwt.VerifierOption{jwt.WithMinRefreshInterval(0)}, wantFetches: 2},
+		{name: "refetches are rate limited", wantFetches: 1, wantErr: jwt.ErrUnknownKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := jwt.NewVerifier(jwt.AccountsKeySource(api), tt.opts...)
+			before := len(srv.Calls("accounts.getJWTPublicKey"))
+
+			oldKey, oldKid := srv.PrivateKey(), srv.KeyID()
+			if _, err := verifier.Verify(context.Background(), gigyatest.SignToken(oldKey, "RS256", oldKid, srv.IDTokenClaims("uid-1"))); err != nil {
+				t.Fatalf("Verify before rotation: %v", err)
+			}
+
+			newKid := srv.RotateKey()
+			_, err := verifier.Verify(context.Background(), gigyatest.SignToken(srv.PrivateKey(), "RS256", newKid, srv.IDTokenClaims("uid-1")))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify after rotation error = %v, want %v", err, tt.wantErr)
+			}
+			if got := len(srv.Calls("accounts.getJWTPublicKey")) - before; got != tt.wantFetches {
+				t.Errorf("keys fetched %d times, want %d", got, tt.wantFetches)
+			}
+
+			// Tokens signed with the previous key stay valid without another fetch
+			fetches := len(srv.Calls("accounts.getJWTPublicKey"))
+			if _, err := verifier.Verify(context.Background(), gigyatest.SignToken(oldKey, "RS256", oldKid, srv.IDTokenClaims("uid-1"))); err != nil {
+				t.Errorf("Verify with the previous key: %v", err)
+			}
+			if got := len(srv.Calls("accounts.getJWTPublicKey")); got != fetches {
+				t.Errorf("known kid fetched the keys again")
+			}
+		})
+	}
+}
+
+func TestVerifyKeySourceError(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	srv.InjectError("accounts.getJWTPublicKey", accounts.ErrorCodeGeneralServerError, 0)
+	verifier := jwt.NewVerifier(jwt.AccountsKeySource(srv.AccountsAPI(accounts.WithRetryPolicy(accounts.RetryPolicy{MaxAttempts: 1}))))
+
+	_, err := verifier.Verify(context.Background(), srv.IDToken(srv.IDTokenClaims("uid-1")))
+	if code := accounts.ErrorCode(err); code != accounts.ErrorCodeGeneralServerError {
+		t.Errorf("Verify error = %v, want the key source error", err)
+	}
+}
+
+func TestVerifyExpiredKeysSourceDown(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	var fetches atomic.Int32
+	source := jwt.KeySourceFunc(func(ctx context.Context) ([]accounts.JWTPublicKey, error) {
+		if fetches.Add(1) > 1 {
+			return nil, errors.New("source down")
+		}
+		return []accounts.JWTPublicKey{jwkOf(srv.KeyID(), "RS256", srv.PrivateKey())}, nil
+	})
+	interval := 200 * time.Millisecond
+	verifier := jwt.NewVerifier(source, jwt.WithCacheTTL(0), jwt.WithMinRefreshInterval(interval))
+	token := srv.IDToken(srv.IDTokenClaims("uid-1"))
+
+	verify := func(want int32) {
+		t.Helper()
+		if _, err := verifier.Verify(context.Background(), token); err != nil {
+			t.Fatalf("Verify with cached keys: %v", err)
+		}
+		if got := fetches.Load(); got != want {
+			t.Fatalf("keys fetched %d times, want %d", got, want)
+		}
+	}
+	verify(1)
+	// The cache expired but the keys were just fetched
+	verify(1)
+	time.Sleep(interval + 50*time.Millisecond)
+	// The refresh fails: the cached keys are used and not fetched again at once
+	verify(2)
+	for i := 0; i < 5; i++ {
+		verify(2)
+	}
+}
+
+func TestVerifyDuringKeyFetch(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	oldKey, oldKid := srv.PrivateKey(), srv.KeyID()
+
+	var fetches atomic.Int32
+	fetching, release := make(chan struct{}), make(chan struct{})
+	source := jwt.KeySourceFunc(func(ctx context.Context) ([]accounts.JWTPublicKey, error) {
+		if fetches.Add(1) > 1 {
+			close(fetching)
+			<-release
+		}
+		return []accounts.JWTPublicKey{jwkOf(srv.KeyID(), "RS256", srv.PrivateKey())}, nil
+	})
+	verifier := jwt.NewVerifier(source, jwt.WithMinRefreshInterval(0))
+	if _, err := verifier.Verify(context.Background(), gigyatest.SignToken(oldKey, "RS256", oldKid, srv.IDTokenClaims("uid-1"))); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// A token with a new kid fetches the keys, which hangs until released
+	newKid := srv.RotateKey()
+	rotated := make(chan error, 1)
+	go func() {
+		_, err := verifier.Verify(context.Background(), gigyatest.SignToken(srv.PrivateKey(), "RS256", newKid, srv.IDTokenClaims("uid-1")))
+		rotated <- err
+	}()
+	<-fetching
+
+	// Tokens with a cached key are verified meanwhile
+	done := make(chan error, 1)
+	go func() {
+		_, err := verifier.Verify(context.Background(), gigyatest.SignToken(oldKey, "RS256", oldKid, srv.IDTokenClaims("uid-1")))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Verify during the fetch: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Verify with a cached key waited for the fetch")
+	}
+
+	close(release)
+	if err := <-rotated; err != nil {
+		t.Errorf("Verify with the new kid: %v", err)
+	}
+}
+
+func TestVerifyClaims(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	source := jwt.AccountsKeySource(srv.AccountsAPI())
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) int64 { return now.Add(d).Unix() }
+
+	// claims returns valid claims with the given changes (nil deletes a claim)
+	claims := func(changes map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":    jwt.GigyaIssuer(srv.APIKey),
+			"apiKey": srv.APIKey,
+			"sub":    "uid-1",
+			"iat":    at(-time.Minute),
+			"exp":    at(4 * time.Minute),
+		}
+		for key, value := range changes {
+			if value == nil {
+				delete(c, key)
+				continue
+			}
+			c[key] = value
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		opts    []jwt.VerifierOption
+		claims  map[string]interface{}
+		wantErr error
+	}{
+		{name: "valid", claims: claims(nil)},
+		{name: "exp missing", claims: claims(map[string]interface{}{"exp": nil}), wantErr: jwt.ErrMissingExpiry},
+		{name: "exp zero", claims: claims(map[string]interface{}{"exp": 0}), wantErr: jwt.ErrMissingExpiry},
+		{name: "expired", claims: claims(map[string]interface{}{"exp": at(-time.Second)}), wantErr: jwt.ErrTokenExpired},
+		{name: "expiring now", claims: claims(map[string]interface{}{"exp": at(0)}), wantErr: jwt.ErrTokenExpired},
+		{name: "expired within the skew", opts: []jwt.VerifierOption{jwt.WithClockSkew(time.Minute)}, claims: claims(map[string]interface{}{"exp": at(-30 * time.Second)})},
+		{name: "expired beyond the skew", opts: []jwt.VerifierOption{jwt.WithClockSkew(time.Minute)}, claims: claims(map[string]interface{}{"exp": at(-2 * time.Minute)}), wantErr: jwt.ErrTokenExpired},
+		{name: "nbf passed", claims: claims(map[string]interface{}{"nbf": at(-time.Second)})},
+		{name: "nbf in the future", claims: claims(map[string]interface{}{"nbf": at(time.Minute)}), wantErr: jwt.ErrTokenNotYetValid},
+		{name: "nbf within the skew", opts: []jwt.VerifierOption{jwt.WithClockSkew(time.Minute)}, claims: claims(map[string]interface{}{"nbf": at(30 * time.Second)})},
+		{name: "iat in the future", claims: claims(map[string]interface{}{"iat": at(time.Minute)}), wantErr: jwt.ErrTokenNotYetValid},
+		{name: "iat missing", claims: claims(map[string]interface{}{"iat": nil})},
+		{name: "within max age", opts: []jwt.VerifierOption{jwt.WithMaxAge(time.Hour)}, claims: claims(map[string]interface{}{"iat": at(-30 * time.Minute)})},
+		{name: "beyond max age", opts: []jwt.VerifierOption{jwt.WithMaxAge(time.Hour)}, claims: claims(map[string]interface{}{"iat": at(-2 * time.Hour)}), wantErr: jwt.ErrTokenTooOld},
+		{name: "max age without iat", opts: []jwt.VerifierOption{jwt.WithMaxAge(time.Hour)}, claims: claims(map[string]interface{}{"iat": nil}), wantErr: jwt.ErrTokenTooOld},
+		{name: "iss of another site", opts: []jwt.VerifierOption{jwt.WithAPIKey(srv.APIKey)}, claims: claims(map[string]interface{}{"iss": jwt.GigyaIssuer("other")}), wantErr: jwt.ErrInvalidIssuer},
+		{name: "iss missing", opts: []jwt.VerifierOption{jwt.WithAPIKey(srv.APIKey)}, claims: claims(map[string]interface{}{"iss": nil}), wantErr: jwt.ErrInvalidIssuer},
+		{name: "apiKey of another site", opts: []jwt.VerifierOption{jwt.WithAPIKey(srv.APIKey)}, claims: claims(map[string]interface{}{"apiKey": "other"}), wantErr: jwt.ErrInvalidAPIKey},
+		{name: "apiKey missing", opts: []jwt.VerifierOption{jwt.WithAPIKey(srv.APIKey)}, claims: claims(map[string]interface{}{"apiKey": nil}), wantErr: jwt.ErrInvalidAPIKey},
+		{name: "apiKey and iss of the site", opts: []jwt.VerifierOption{jwt.WithAPIKey(srv.APIKey)}, claims: claims(nil)},
+		{name: "custom issuer", opts: []jwt.VerifierOption{jwt.WithAPIKey(srv.APIKey), jwt.WithIssuer("https://issuer.example.com/")}, claims: claims(map[string]interface{}{"iss": "https://issuer.example.com/"})},
+		{name: "site issuer with a custom issuer", opts: []jwt.VerifierOption{jwt.WithAPIKey(srv.APIKey), jwt.WithIssuer("https://issuer.example.com/")}, claims: claims(nil), wantErr: jwt.ErrInvalidIssuer},
+		{name: "unchecked iss and apiKey", claims: claims(map[string]interface{}{"iss": "anyone", "apiKey": "other"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]jwt.VerifierOption{jwt.WithClock(func() time.Time { return now })}, tt.opts...)
+			token := srv.IDToken(tt.claims)
+
+			got, err := jwt.NewVerifier(source, opts...).Verify(context.Background(), token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (got.Subject != "uid-1" || got.Raw["sub"] != "uid-1") {
+				t.Errorf("claims = %+v, want sub uid-1", got)
+			}
+		})
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	srv := gigyatest.NewServer()
+	defer srv.Close()
+	api := srv.AccountsAPI()
+	uid := srv.AddAccount(accounts.Account{Profile: accounts.Profile{Email: "jane@example.com", FirstName: "Jane"}})
+	verifier := jwt.NewVerifier(jwt.AccountsKeySource(api), jwt.WithAPIKey(srv.APIKey))
+
+	tests := []struct {
+		name    string
+		token   func(t *testing.T) string
+		wantErr error
+	}{
+		{
+			name: "issued by getJWT",
+			token: func(t *testing.T) string {
+				token, err := api.GetJWT(uid, []string{"profile.firstName", "profile.email"}, time.Minute)
+				if err != nil {
+					t.Fatalf("GetJWT: %v", err)
+				}
+				return token
+			},
+		},
+		{
+			name: "without sub",
+			token: func(t *testing.T) string {
+				claims := srv.IDTokenClaims(uid)
+				delete(claims, "sub")
+				return srv.IDToken(claims)
+			},
+			wantErr: jwt.ErrMissingSubject,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.VerifyIDToken(context.Background(), tt.token(t))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyIDToken error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if claims.UID() != uid || claims.APIKey != srv.APIKey {
+				t.Errorf("UID = %q, apiKey = %q, want %q and %q", claims.UID(), claims.APIKey, uid, srv.APIKey)
+			}
+			if claims.Profile == nil || claims.Profile.FirstName != "Jane" || claims.Profile.Email != "jane@example.com" {
+				t.Errorf("Profile = %+v, want the requested fields", claims.Profile)
+			}
+		})
+	}
+}